@@ -9,7 +9,8 @@ import (
 
 func main() {
 	fmt.Println("FastEmbed Go Bindings Example")
-	fmt.Println("==============================\n")
+	fmt.Println("==============================")
+	fmt.Println()
 
 	// Example 1: Text Embeddings
 	textEmbeddingExample()
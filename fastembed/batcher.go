@@ -0,0 +1,238 @@
+package fastembed
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Default Batcher settings
+const (
+	DefaultBatchWindow  = 5 * time.Millisecond
+	DefaultMaxBatchSize = 256
+)
+
+// BatcherOption configures a Batcher
+type BatcherOption func(*batcherOptions)
+
+type batcherOptions struct {
+	window       time.Duration
+	maxBatchSize int
+}
+
+// WithBatchWindow sets how long a Batcher waits for more calls after the
+// first call of a batch arrives
+func WithBatchWindow(d time.Duration) BatcherOption {
+	return func(o *batcherOptions) {
+		o.window = d
+	}
+}
+
+// WithMaxBatchSize sets the number of texts at which a Batcher runs a batch
+// without waiting for the rest of the window
+func WithMaxBatchSize(n int) BatcherOption {
+	return func(o *batcherOptions) {
+		o.maxBatchSize = n
+	}
+}
+
+// Batcher coalesces concurrent Embed calls with few texts each into one
+// model call. Calls arriving within the batch window of the first one, up to
+// the maximum batch size, are embedded together and each caller receives
+// the results for its own texts. It is safe for concurrent use.
+type Batcher[R any] struct {
+	embed        func(ctx context.Context, texts []string, batchSize int) ([]R, error)
+	window       time.Duration
+	maxBatchSize int
+
+	mu       sync.RWMutex
+	closed   bool
+	requests chan *batchRequest[R]
+	stopped  chan struct{}
+}
+
+type batchRequest[R any] struct {
+	ctx   context.Context
+	texts []string
+	done  chan batchResult[R]
+}
+
+type batchResult[R any] struct {
+	embeddings []R
+	err        error
+}
+
+// NewTextBatcher returns a Batcher that embeds with a TextEmbedding. Closing
+// the Batcher does not close the model.
+func NewTextBatcher(model *TextEmbedding, opts ...BatcherOption) *Batcher[[]float32] {
+	return newBatcher(func(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+		return model.EmbedContext(ctx, texts, WithBatchSize(batchSize))
+	}, opts)
+}
+
+// NewSparseBatcher returns a Batcher that embeds with a SparseTextEmbedding.
+// Closing the Batcher does not close the model.
+func NewSparseBatcher(model *SparseTextEmbedding, opts ...BatcherOption) *Batcher[SparseEmbedding] {
+	return newBatcher(func(ctx context.Context, texts []string, batchSize int) ([]SparseEmbedding, error) {
+		return model.EmbedContext(ctx, texts, WithBatchSize(batchSize))
+	}, opts)
+}
+
+func newBatcher[R any](embed func(context.Context, []string, int) ([]R, error), opts []BatcherOption) *Batcher[R] {
+	o := &batcherOptions{
+		window:       DefaultBatchWindow,
+		maxBatchSize: DefaultMaxBatchSize,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	if o.maxBatchSize < 1 {
+		o.maxBatchSize = 1
+	}
+
+	b := &Batcher[R]{
+		embed:        embed,
+		window:       o.window,
+		maxBatchSize: o.maxBatchSize,
+		requests:     make(chan *batchRequest[R]),
+		stopped:      make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Embed embeds texts as part of the next batch. It returns ctx.Err() as soon
+// as ctx is done, without affecting the other callers of the batch.
+func (b *Batcher[R]) Embed(ctx context.Context, texts []string) ([]R, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(texts) == 0 {
+		return []R{}, nil
+	}
+	// Reject invalid texts here so they cannot fail the other callers' batch
+	if _, _, err := validateInputs(texts, false, checkText); err != nil {
+		return nil, err
+	}
+
+	req := &batchRequest[R]{ctx: ctx, texts: texts, done: make(chan batchResult[R], 1)}
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return nil, ErrClosed
+	}
+	select {
+	case b.requests <- req:
+		b.mu.RUnlock()
+	case <-ctx.Done():
+		b.mu.RUnlock()
+		return nil, ctx.Err()
+	}
+
+	select {
+	case res := <-req.done:
+		return res.embeddings, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close runs the pending calls and stops the Batcher. Later Embed calls
+// return ErrClosed.
+func (b *Batcher[R]) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		<-b.stopped
+		return
+	}
+	b.closed = true
+	close(b.requests)
+	b.mu.Unlock()
+	<-b.stopped
+}
+
+func (b *Batcher[R]) run() {
+	defer close(b.stopped)
+	for req := range b.requests {
+		batch := []*batchRequest[R]{req}
+		size := len(req.texts)
+
+		timer := time.NewTimer(b.window)
+	collect:
+		for size < b.maxBatchSize {
+			select {
+			case req, ok := <-b.requests:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, req)
+				size += len(req.texts)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		b.flush(batch)
+	}
+}
+
+// flush embeds the texts of all live requests in one call and hands each
+// request its share of the results
+func (b *Batcher[R]) flush(batch []*batchRequest[R]) {
+	live := batch[:0]
+	var texts []string
+	for _, req := range batch {
+		if err := req.ctx.Err(); err != nil {
+			req.done <- batchResult[R]{err: err}
+			continue
+		}
+		live = append(live, req)
+		texts = append(texts, req.texts...)
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	ctx, cancel := batchContext(live)
+	defer cancel()
+	embeddings, err := b.embed(ctx, texts, b.maxBatchSize)
+
+	offset := 0
+	for _, req := range live {
+		if err != nil {
+			req.done <- batchResult[R]{err: err}
+			continue
+		}
+		n := len(req.texts)
+		req.done <- batchResult[R]{embeddings: embeddings[offset : offset+n : offset+n]}
+		offset += n
+	}
+}
+
+// batchContext returns a context that is cancelled once the contexts of all
+// requests are done, so a batch stops early only when nobody waits for it
+func batchContext[R any](batch []*batchRequest[R]) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	pending := len(batch)
+	stops := make([]func() bool, 0, len(batch))
+	for _, req := range batch {
+		stops = append(stops, context.AfterFunc(req.ctx, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if pending--; pending == 0 {
+				cancel()
+			}
+		}))
+	}
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,179 @@
+package fastembed
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lengthBatcher returns a Batcher whose embeddings are the input lengths,
+// recording the size of every model call
+func lengthBatcher(opts ...BatcherOption) (*Batcher[int], *[]int) {
+	var mu sync.Mutex
+	var calls []int
+	b := newBatcher(func(ctx context.Context, texts []string, batchSize int) ([]int, error) {
+		mu.Lock()
+		calls = append(calls, len(texts))
+		mu.Unlock()
+		out := make([]int, len(texts))
+		for i, text := range texts {
+			out[i] = len(text)
+		}
+		return out, nil
+	}, opts)
+	return b, &calls
+}
+
+func TestBatcher_Coalesces(t *testing.T) {
+	b, calls := lengthBatcher(WithBatchWindow(50*time.Millisecond), WithMaxBatchSize(100))
+	defer b.Close()
+
+	inputs := [][]string{{"a"}, {"bb", "ccc"}, {"dddd"}, {"eeeee", "ffffff"}}
+	var wg sync.WaitGroup
+	for _, texts := range inputs {
+		wg.Add(1)
+		go func(texts []string) {
+			defer wg.Done()
+			got, err := b.Embed(context.Background(), texts)
+			if err != nil {
+				t.Errorf("Failed to embed: %v", err)
+				return
+			}
+			if len(got) != len(texts) {
+				t.Errorf("Expected %d results, got %d", len(texts), len(got))
+				return
+			}
+			for i, text := range texts {
+				if got[i] != len(text) {
+					t.Errorf("Result %d of %v is %d, expected %d", i, texts, got[i], len(text))
+				}
+			}
+		}(texts)
+	}
+	wg.Wait()
+
+	if len(*calls) != 1 || (*calls)[0] != 6 {
+		t.Errorf("Expected one model call with 6 texts, got %v", *calls)
+	}
+}
+
+func TestBatcher_MaxBatchSize(t *testing.T) {
+	b, calls := lengthBatcher(WithBatchWindow(time.Hour), WithMaxBatchSize(2))
+	defer b.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := b.Embed(context.Background(), []string{"x"}); err != nil {
+				t.Errorf("Failed to embed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(*calls) != 2 {
+		t.Errorf("Expected two full batches, got %v", *calls)
+	}
+}
+
+func TestBatcher_CallerCancel(t *testing.T) {
+	release := make(chan struct{})
+	var seen []string
+	b := newBatcher(func(ctx context.Context, texts []string, batchSize int) ([]int, error) {
+		seen = texts
+		<-release
+		return make([]int, len(texts)), ctx.Err()
+	}, []BatcherOption{WithBatchWindow(50 * time.Millisecond)})
+	defer b.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := b.Embed(ctx, []string{"cancelled"})
+		cancelled <- err
+	}()
+	kept := make(chan error, 1)
+	go func() {
+		_, err := b.Embed(context.Background(), []string{"kept"})
+		kept <- err
+	}()
+
+	// Cancel while the batch runs: the caller returns, the others continue
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-cancelled; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	close(release)
+	if err := <-kept; err != nil {
+		t.Errorf("Expected the other caller to succeed, got %v", err)
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected both texts in one batch, got %v", seen)
+	}
+}
+
+func TestBatcher_Close(t *testing.T) {
+	b, _ := lengthBatcher(WithBatchWindow(time.Hour))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.Embed(context.Background(), []string{"pending"})
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	b.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Expected the pending call to run on close, got %v", err)
+	}
+	if _, err := b.Embed(context.Background(), []string{"late"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	b.Close()
+}
+
+func TestTextBatcher(t *testing.T) {
+	model, err := NewTextEmbedding("")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer model.Close()
+
+	b := NewTextBatcher(model)
+	defer b.Close()
+
+	texts := []string{"Hello, World!", "This is a test.", "Batching works."}
+	want, err := model.Embed(texts, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i, text := range texts {
+		wg.Add(1)
+		go func(i int, text string) {
+			defer wg.Done()
+			got, err := b.Embed(context.Background(), []string{text})
+			if err != nil {
+				t.Errorf("Failed to embed with batcher: %v", err)
+				return
+			}
+			if len(got) != 1 || len(got[0]) != len(want[i]) {
+				t.Errorf("Unexpected embedding shape for %q", text)
+				return
+			}
+			for j := range got[0] {
+				if diff := got[0][j] - want[i][j]; diff > 1e-4 || diff < -1e-4 {
+					t.Errorf("Embedding of %q differs at %d: %f vs %f", text, j, got[0][j], want[i][j])
+					return
+				}
+			}
+		}(i, text)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,323 @@
+package fastembed
+
+/*
+#include "fastembed.h"
+*/
+import "C"
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModelKind identifies which kind of model a model code belongs to
+type ModelKind int
+
+const (
+	// KindUnknown is used for cached repositories that are not a supported model
+	KindUnknown             ModelKind = 0
+	KindTextEmbedding       ModelKind = C.FASTEMBED_MODEL_KIND_TEXT_EMBEDDING
+	KindSparseTextEmbedding ModelKind = C.FASTEMBED_MODEL_KIND_SPARSE_TEXT_EMBEDDING
+	KindImageEmbedding      ModelKind = C.FASTEMBED_MODEL_KIND_IMAGE_EMBEDDING
+	KindTextRerank          ModelKind = C.FASTEMBED_MODEL_KIND_TEXT_RERANK
+)
+
+// String returns the name of the model kind
+func (k ModelKind) String() string {
+	switch k {
+	case KindTextEmbedding:
+		return "text-embedding"
+	case KindSparseTextEmbedding:
+		return "sparse-text-embedding"
+	case KindImageEmbedding:
+		return "image-embedding"
+	case KindTextRerank:
+		return "text-rerank"
+	default:
+		return "unknown"
+	}
+}
+
+// lastUsedFile is touched by the Rust library each time a model is loaded
+const lastUsedFile = ".fastembed_last_used"
+
+// CachedModel describes a model stored in the model cache
+type CachedModel struct {
+	ModelCode string    // Hugging Face model code, e.g. "Xenova/bge-small-en-v1.5"
+	Kind      ModelKind // Model kind, KindUnknown for unsupported repositories
+	Dir       string    // Cache directory of the model
+	Size      int64     // Size of the downloaded files in bytes
+	Files     []string  // Cached files, relative to the model snapshot
+	LastUsed  time.Time // Last time the model was loaded, or downloaded if never loaded
+}
+
+// CacheProblem describes a cached file that failed verification
+type CacheProblem struct {
+	File    string // File path relative to the model cache directory
+	Problem string // Description of the problem
+}
+
+// DefaultCacheDir returns the model cache directory used when WithCacheDir is
+// not given
+func DefaultCacheDir() string {
+	cDir := C.fastembed_default_cache_dir()
+	if cDir == nil {
+		return ""
+	}
+	defer C.fastembed_string_free(cDir)
+	return C.GoString(cDir)
+}
+
+// modelDirName returns the Hugging Face cache directory name of a model code
+func modelDirName(modelCode string) string {
+	return "models--" + strings.ReplaceAll(modelCode, "/", "--")
+}
+
+// modelCodeFromDir returns the model code of a Hugging Face cache directory
+// name. Only the first "--" separates owner and repository, as owner names
+// cannot contain it but repository names can.
+func modelCodeFromDir(name string) string {
+	owner, repo, found := strings.Cut(strings.TrimPrefix(name, "models--"), "--")
+	if !found {
+		return owner
+	}
+	return owner + "/" + repo
+}
+
+// cacheDirOrDefault returns cacheDir, or the default cache directory if empty
+func cacheDirOrDefault(cacheDir string) string {
+	if cacheDir == "" {
+		return DefaultCacheDir()
+	}
+	return cacheDir
+}
+
+// modelKinds maps every supported model code to its kind
+func modelKinds() map[string]ModelKind {
+	kinds := make(map[string]ModelKind)
+	for kind, models := range map[ModelKind][]ModelInfo{
+		KindTextEmbedding:       ListTextEmbeddingModels(),
+		KindSparseTextEmbedding: ListSparseTextEmbeddingModels(),
+		KindImageEmbedding:      ListImageEmbeddingModels(),
+		KindTextRerank:          ListTextRerankModels(),
+	} {
+		for _, m := range models {
+			kinds[strings.ToLower(m.ModelCode)] = kind
+		}
+	}
+	return kinds
+}
+
+// ListCachedModels returns the models stored in cacheDir, sorted by model
+// code. An empty cacheDir selects DefaultCacheDir. A missing cache directory
+// yields an empty list.
+func ListCachedModels(cacheDir string) ([]CachedModel, error) {
+	cacheDir = cacheDirOrDefault(cacheDir)
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fastembed: reading cache directory: %w", err)
+	}
+
+	kinds := modelKinds()
+	var models []CachedModel
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "models--") {
+			continue
+		}
+		modelCode := modelCodeFromDir(entry.Name())
+		model, err := readCachedModel(filepath.Join(cacheDir, entry.Name()), modelCode)
+		if err != nil {
+			return nil, err
+		}
+		model.Kind = kinds[strings.ToLower(modelCode)]
+		models = append(models, model)
+	}
+
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].ModelCode < models[j].ModelCode
+	})
+	return models, nil
+}
+
+// readCachedModel collects size, files and usage time of one cached model
+func readCachedModel(dir, modelCode string) (CachedModel, error) {
+	model := CachedModel{ModelCode: modelCode, Dir: dir}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() && info.ModTime().After(model.LastUsed) {
+			model.LastUsed = info.ModTime()
+		}
+
+		switch parts := strings.SplitN(filepath.ToSlash(rel), "/", 3); {
+		case parts[0] == lastUsedFile:
+			// Usage marker; its modification time is the last use
+		case parts[0] == "blobs":
+			model.Size += info.Size()
+		case parts[0] == "snapshots" && len(parts) == 3:
+			model.Files = append(model.Files, parts[2])
+			if info.Mode().IsRegular() {
+				// Platforms without symlinks store the files in the snapshot
+				model.Size += info.Size()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return CachedModel{}, fmt.Errorf("fastembed: reading cached model %s: %w", modelCode, err)
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, lastUsedFile)); err == nil {
+		model.LastUsed = info.ModTime()
+	}
+	sort.Strings(model.Files)
+	return model, nil
+}
+
+// VerifyCachedModel checks the integrity of the files of a cached model. Each
+// downloaded blob is named after its Hugging Face ETag, which is the SHA-256
+// of the file for LFS files and the git blob SHA-1 otherwise, and is hashed
+// and compared against it. Snapshot links that point to missing blobs and
+// unfinished downloads are reported as well. An empty result means all files
+// are intact.
+func VerifyCachedModel(cacheDir, modelCode string) ([]CacheProblem, error) {
+	dir := filepath.Join(cacheDirOrDefault(cacheDir), modelDirName(modelCode))
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("fastembed: model %s is not cached: %w", modelCode, err)
+	}
+
+	var problems []CacheProblem
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+		if len(parts) != 2 {
+			return nil
+		}
+
+		switch parts[0] {
+		case "blobs":
+			if problem := verifyBlob(path, d.Name()); problem != "" {
+				problems = append(problems, CacheProblem{File: filepath.ToSlash(rel), Problem: problem})
+			}
+		case "snapshots":
+			if _, err := os.Stat(path); err != nil {
+				problems = append(problems, CacheProblem{File: filepath.ToSlash(rel), Problem: "broken link to missing blob"})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fastembed: verifying cached model %s: %w", modelCode, err)
+	}
+	return problems, nil
+}
+
+// verifyBlob hashes a blob and compares it with its ETag file name. It
+// returns a description of the problem, or "" if the blob is intact or its
+// name is not a known hash.
+func verifyBlob(path, name string) string {
+	var h hash.Hash
+	switch {
+	case strings.HasSuffix(name, ".part") || strings.HasSuffix(name, ".incomplete"):
+		return "incomplete download"
+	case len(name) == sha256.Size*2 && isHex(name):
+		h = sha256.New()
+	case len(name) == sha1.Size*2 && isHex(name):
+		h = sha1.New()
+	default:
+		return ""
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Sprintf("unreadable: %v", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Sprintf("unreadable: %v", err)
+	}
+
+	if len(name) == sha1.Size*2 {
+		// Git blob hash of a regular (non-LFS) file
+		fmt.Fprintf(h, "blob %d\x00", info.Size())
+	}
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Sprintf("unreadable: %v", err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != strings.ToLower(name) {
+		return fmt.Sprintf("checksum mismatch: got %s", sum)
+	}
+	return ""
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// DeleteCachedModel removes a model and its download locks from the cache
+func DeleteCachedModel(cacheDir, modelCode string) error {
+	cacheDir = cacheDirOrDefault(cacheDir)
+	dir := filepath.Join(cacheDir, modelDirName(modelCode))
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("fastembed: model %s is not cached: %w", modelCode, err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("fastembed: deleting cached model %s: %w", modelCode, err)
+	}
+	if err := os.RemoveAll(filepath.Join(cacheDir, ".locks", modelDirName(modelCode))); err != nil {
+		return fmt.Errorf("fastembed: deleting download locks of %s: %w", modelCode, err)
+	}
+	return nil
+}
+
+// PruneCache deletes every cached model that has not been used since the
+// given time and returns the deleted models
+func PruneCache(cacheDir string, unusedSince time.Time) ([]CachedModel, error) {
+	models, err := ListCachedModels(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []CachedModel
+	for _, m := range models {
+		if !m.LastUsed.Before(unusedSince) {
+			continue
+		}
+		if err := DeleteCachedModel(cacheDir, m.ModelCode); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, m)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,197 @@
+package fastembed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCachedModel creates a Hugging Face cache entry for modelCode in
+// cacheDir with the given files and returns the model directory
+func writeCachedModel(t *testing.T, cacheDir, modelCode string, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(cacheDir, modelDirName(modelCode))
+	snapshot := filepath.Join(dir, "snapshots", "0123456789abcdef0123456789abcdef01234567")
+	for _, d := range []string{filepath.Join(dir, "blobs"), filepath.Join(dir, "refs"), snapshot} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatalf("Failed to create cache directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "refs", "main"), []byte(filepath.Base(snapshot)), 0o644); err != nil {
+		t.Fatalf("Failed to write ref: %v", err)
+	}
+	for name, content := range files {
+		sum := sha256.Sum256([]byte(content))
+		blob := filepath.Join(dir, "blobs", hex.EncodeToString(sum[:]))
+		if err := os.WriteFile(blob, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write blob: %v", err)
+		}
+		if err := os.Symlink(blob, filepath.Join(snapshot, name)); err != nil {
+			t.Fatalf("Failed to link snapshot file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestListCachedModels(t *testing.T) {
+	cacheDir := t.TempDir()
+	writeCachedModel(t, cacheDir, "org/model-b", map[string]string{"model.onnx": "onnx", "tokenizer.json": "{}"})
+	writeCachedModel(t, cacheDir, "org/model-a", map[string]string{"model.onnx": "a"})
+	if err := os.MkdirAll(filepath.Join(cacheDir, ".locks"), 0o755); err != nil {
+		t.Fatalf("Failed to create locks directory: %v", err)
+	}
+
+	models, err := ListCachedModels(cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to list cached models: %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("Expected 2 cached models, got %d", len(models))
+	}
+	if models[0].ModelCode != "org/model-a" || models[1].ModelCode != "org/model-b" {
+		t.Errorf("Unexpected model codes: %s, %s", models[0].ModelCode, models[1].ModelCode)
+	}
+	if models[1].Size != int64(len("onnx")+len("{}")) {
+		t.Errorf("Expected size 6, got %d", models[1].Size)
+	}
+	if len(models[1].Files) != 2 || models[1].Files[0] != "model.onnx" || models[1].Files[1] != "tokenizer.json" {
+		t.Errorf("Unexpected files: %v", models[1].Files)
+	}
+	if models[0].Kind != KindUnknown {
+		t.Errorf("Expected unknown kind, got %s", models[0].Kind)
+	}
+
+	models, err = ListCachedModels(filepath.Join(cacheDir, "missing"))
+	if err != nil || len(models) != 0 {
+		t.Errorf("Expected no models for a missing cache, got %v, %v", models, err)
+	}
+}
+
+func TestModelCodeFromDir(t *testing.T) {
+	for _, modelCode := range []string{"org/model", "org/model--v2", "org/a--b--c", "gpt2"} {
+		if got := modelCodeFromDir(modelDirName(modelCode)); got != modelCode {
+			t.Errorf("Expected %q, got %q", modelCode, got)
+		}
+	}
+}
+
+func TestCachedModel_LastUsed(t *testing.T) {
+	cacheDir := t.TempDir()
+	dir := writeCachedModel(t, cacheDir, "org/model", map[string]string{"model.onnx": "onnx"})
+
+	used := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	marker := filepath.Join(dir, lastUsedFile)
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		t.Fatalf("Failed to write marker: %v", err)
+	}
+	if err := os.Chtimes(marker, used, used); err != nil {
+		t.Fatalf("Failed to set marker time: %v", err)
+	}
+
+	models, err := ListCachedModels(cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to list cached models: %v", err)
+	}
+	if !models[0].LastUsed.Equal(used) {
+		t.Errorf("Expected last used %v, got %v", used, models[0].LastUsed)
+	}
+}
+
+func TestVerifyCachedModel(t *testing.T) {
+	cacheDir := t.TempDir()
+	dir := writeCachedModel(t, cacheDir, "org/model", map[string]string{"model.onnx": "onnx", "config.json": "{}"})
+
+	problems, err := VerifyCachedModel(cacheDir, "org/model")
+	if err != nil {
+		t.Fatalf("Failed to verify cached model: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Fatalf("Expected an intact model, got %v", problems)
+	}
+
+	sum := sha256.Sum256([]byte("onnx"))
+	if err := os.WriteFile(filepath.Join(dir, "blobs", hex.EncodeToString(sum[:])), []byte("corrupt"), 0o644); err != nil {
+		t.Fatalf("Failed to corrupt blob: %v", err)
+	}
+	sum = sha256.Sum256([]byte("{}"))
+	if err := os.Remove(filepath.Join(dir, "blobs", hex.EncodeToString(sum[:]))); err != nil {
+		t.Fatalf("Failed to remove blob: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "blobs", "abc.incomplete"), nil, 0o644); err != nil {
+		t.Fatalf("Failed to write partial blob: %v", err)
+	}
+
+	problems, err = VerifyCachedModel(cacheDir, "org/model")
+	if err != nil {
+		t.Fatalf("Failed to verify cached model: %v", err)
+	}
+	if len(problems) != 3 {
+		t.Errorf("Expected 3 problems, got %v", problems)
+	}
+
+	if _, err := VerifyCachedModel(cacheDir, "org/missing"); err == nil {
+		t.Error("Expected an error for a model that is not cached")
+	}
+}
+
+func TestVerifyBlob_GitHash(t *testing.T) {
+	dir := t.TempDir()
+	// git hash-object of a file containing "hello\n"
+	path := filepath.Join(dir, "ce013625030ba8dba906f756967f9e9ca394464a")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write blob: %v", err)
+	}
+	if problem := verifyBlob(path, filepath.Base(path)); problem != "" {
+		t.Errorf("Expected an intact blob, got %q", problem)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	oldDir := writeCachedModel(t, cacheDir, "org/old", map[string]string{"model.onnx": "old"})
+	writeCachedModel(t, cacheDir, "org/new", map[string]string{"model.onnx": "new"})
+
+	old := time.Now().Add(-48 * time.Hour)
+	if err := filepath.Walk(oldDir, func(path string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Chtimes(path, old, old)
+	}); err != nil {
+		t.Fatalf("Failed to age model files: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(cacheDir, ".locks", modelDirName("org/old")), 0o755); err != nil {
+		t.Fatalf("Failed to create lock directory: %v", err)
+	}
+
+	pruned, err := PruneCache(cacheDir, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to prune cache: %v", err)
+	}
+	if len(pruned) != 1 || pruned[0].ModelCode != "org/old" {
+		t.Fatalf("Expected org/old to be pruned, got %v", pruned)
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Error("Expected pruned model directory to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, ".locks", modelDirName("org/old"))); !os.IsNotExist(err) {
+		t.Error("Expected pruned model locks to be removed")
+	}
+
+	models, err := ListCachedModels(cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to list cached models: %v", err)
+	}
+	if len(models) != 1 || models[0].ModelCode != "org/new" {
+		t.Errorf("Expected only org/new to remain, got %v", models)
+	}
+}
+
+func TestDefaultCacheDir(t *testing.T) {
+	if DefaultCacheDir() == "" {
+		t.Error("Expected a default cache directory")
+	}
+}
@@ -0,0 +1,260 @@
+package fastembed
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChunkPooling selects how EmbedDocuments combines the chunk embeddings of
+// a document
+type ChunkPooling int
+
+const (
+	ChunkPoolingMean     ChunkPooling = iota // Average of the chunk embeddings
+	ChunkPoolingWeighted                     // Average weighted by the tokens of each chunk
+)
+
+// Chunk is a piece of a document embedded on its own by EmbedChunks
+type Chunk struct {
+	Start     int // Byte offset of the chunk in the document
+	End       int // Byte offset just past the chunk, so the text is document[Start:End]
+	Tokens    int // Tokens of the chunk, without special tokens
+	Embedding []float32
+}
+
+// WithChunkSize sets the maximum tokens per chunk for EmbedChunks and
+// EmbedDocuments, including the special tokens the tokenizer adds. Zero uses
+// the model's MaxLength. Chunks are cut between words, so that a chunk
+// tokenizes into the tokens it was planned with and is never truncated.
+// Only a single word longer than a chunk is cut inside the word.
+func WithChunkSize(tokens int) CallOption {
+	return func(o *callOptions) {
+		o.chunkSize = tokens
+	}
+}
+
+// WithChunkOverlap sets how many tokens consecutive chunks share, so that
+// text at a chunk boundary is embedded with context. Chunks start at a word,
+// so they may share a few more tokens. The default is 0.
+func WithChunkOverlap(tokens int) CallOption {
+	return func(o *callOptions) {
+		o.chunkOverlap = tokens
+	}
+}
+
+// WithChunkPooling sets how EmbedDocuments combines the chunks of a
+// document. The default is ChunkPoolingMean.
+func WithChunkPooling(pooling ChunkPooling) CallOption {
+	return func(o *callOptions) {
+		o.chunkPooling = pooling
+	}
+}
+
+// EmbedChunks splits each document into chunks that fit the model and
+// embeds every chunk, instead of truncating long documents to MaxLength.
+// Chunks are cut between words; their size and overlap are set with
+// WithChunkSize and WithChunkOverlap. A document that fits the model
+// yields one chunk. With WithSkipInvalid, skipped documents get no chunks.
+func (te *TextEmbedding) EmbedChunks(ctx context.Context, documents []string, opts ...CallOption) ([][]Chunk, error) {
+	o := newCallOptions(opts)
+	valid, kept, err := validateInputs(documents, o.skipInvalid, checkText)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := te.planChunks(ctx, valid, o)
+	if err != nil {
+		return nil, err
+	}
+
+	var texts []string
+	for i, docChunks := range chunks {
+		for _, c := range docChunks {
+			texts = append(texts, valid[i][c.Start:c.End])
+		}
+	}
+	embeddings, err := te.EmbedContext(ctx, texts, WithBatchSize(o.batchSize))
+	if err != nil {
+		return nil, err
+	}
+
+	next := 0
+	for _, docChunks := range chunks {
+		for j := range docChunks {
+			docChunks[j].Embedding = embeddings[next]
+			next++
+		}
+	}
+	return spread(chunks, kept, len(documents)), nil
+}
+
+// EmbedDocuments embeds each document as a whole, however long: it embeds
+// the chunks of the document like EmbedChunks and pools them into one vector
+// of unit length, as set with WithChunkPooling. With WithSkipInvalid,
+// skipped documents get a nil embedding.
+func (te *TextEmbedding) EmbedDocuments(ctx context.Context, documents []string, opts ...CallOption) ([][]float32, error) {
+	chunks, err := te.EmbedChunks(ctx, documents, opts...)
+	if err != nil {
+		return nil, err
+	}
+	pooling := newCallOptions(opts).chunkPooling
+
+	embeddings := make([][]float32, len(chunks))
+	for i, docChunks := range chunks {
+		if docChunks != nil {
+			embeddings[i] = poolChunks(docChunks, pooling)
+		}
+	}
+	return embeddings, nil
+}
+
+// planChunks tokenizes each document and returns the spans of its chunks
+func (te *TextEmbedding) planChunks(ctx context.Context, documents []string, o *callOptions) ([][]Chunk, error) {
+	size := o.chunkSize
+	if size == 0 {
+		size = te.MaxLength()
+	}
+	if size <= 0 || o.chunkOverlap < 0 {
+		return nil, &Error{
+			message: fmt.Sprintf("invalid chunk size %d with overlap %d", size, o.chunkOverlap),
+			code:    ErrorInvalidInput,
+		}
+	}
+
+	chunks := make([][]Chunk, len(documents))
+	for i, doc := range documents {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		tokens, err := te.Tokenize(doc)
+		if err != nil {
+			return nil, err
+		}
+		chunks[i], err = chunkSpans(doc, tokens, size, o.chunkOverlap)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return chunks, nil
+}
+
+// chunkSpans splits the tokens of text into windows of at most size
+// tokens, special tokens included, that overlap by at least overlap tokens.
+// Special tokens have empty offset ranges; every chunk gets them again when
+// it is embedded. Windows start and end at word starts, as text cut inside
+// a word can tokenize into more tokens than the word had in the document.
+func chunkSpans(text string, tokens Tokens, size, overlap int) ([]Chunk, error) {
+	var content [][2]int
+	var words []bool
+	for i, starts := range wordStarts(text, tokens) {
+		if offsets := tokens.Offsets[i]; offsets[1] > offsets[0] {
+			content = append(content, offsets)
+			words = append(words, starts)
+		}
+	}
+	budget := size - (len(tokens.Offsets) - len(content))
+	if budget <= overlap {
+		return nil, &Error{
+			message: fmt.Sprintf("chunk size %d leaves %d tokens per chunk, which must be more than the overlap of %d", size, budget, overlap),
+			code:    ErrorInvalidInput,
+		}
+	}
+	if len(content) == 0 {
+		return []Chunk{{Start: 0, End: len(text)}}, nil
+	}
+
+	var chunks []Chunk
+	for first := 0; ; {
+		last := min(first+budget, len(content))
+		// End before the word the window would split, unless the word
+		// alone fills the window
+		end := last
+		for end < len(content) && end > first && !words[end] {
+			end--
+		}
+		if end > first {
+			last = end
+		}
+		chunks = append(chunks, Chunk{
+			Start:  content[first][0],
+			End:    content[last-1][1],
+			Tokens: last - first,
+		})
+		if last == len(content) {
+			return chunks, nil
+		}
+
+		// Start the next window at a word start, overlapping more if needed
+		next := max(last-overlap, first+1)
+		start := next
+		for start > first && !words[start] {
+			start--
+		}
+		if start > first {
+			next = start
+		}
+		first = next
+	}
+}
+
+// wordStarts reports for each token whether it starts a word. WordPiece
+// tokenizers mark the tokens continuing a word with "##". For other
+// tokenizers a token starts a word if whitespace or a word boundary marker
+// ("▁" for SentencePiece, "Ġ" for byte-level BPE) comes before its text.
+func wordStarts(text string, tokens Tokens) []bool {
+	wordPiece := false
+	for _, token := range tokens.Tokens {
+		if strings.HasPrefix(token, "##") {
+			wordPiece = true
+			break
+		}
+	}
+
+	starts := make([]bool, len(tokens.Offsets))
+	for i, offsets := range tokens.Offsets {
+		var token string
+		if i < len(tokens.Tokens) {
+			token = tokens.Tokens[i]
+		}
+		if wordPiece {
+			starts[i] = !strings.HasPrefix(token, "##")
+			continue
+		}
+		start := offsets[0]
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		at, _ := utf8.DecodeRuneInString(text[start:])
+		starts[i] = start == 0 || unicode.IsSpace(before) || unicode.IsSpace(at) ||
+			strings.HasPrefix(token, "▁") || strings.HasPrefix(token, "Ġ")
+	}
+	return starts
+}
+
+// poolChunks averages the chunk embeddings of a document and normalizes
+// the result to unit length
+func poolChunks(chunks []Chunk, pooling ChunkPooling) []float32 {
+	pooled := make([]float32, len(chunks[0].Embedding))
+	for _, c := range chunks {
+		weight := float32(1)
+		if pooling == ChunkPoolingWeighted {
+			weight = float32(max(c.Tokens, 1))
+		}
+		for j, v := range c.Embedding {
+			pooled[j] += weight * v
+		}
+	}
+
+	var norm float64
+	for _, v := range pooled {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for j := range pooled {
+			pooled[j] *= scale
+		}
+	}
+	return pooled
+}
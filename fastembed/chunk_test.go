@@ -0,0 +1,149 @@
+package fastembed
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestChunkSpans(t *testing.T) {
+	// "a b c d e" with [CLS] and [SEP]
+	text := "a b c d e"
+	tokens := Tokens{
+		Tokens:  []string{"[CLS]", "a", "b", "c", "d", "e", "[SEP]"},
+		Offsets: [][2]int{{0, 0}, {0, 1}, {2, 3}, {4, 5}, {6, 7}, {8, 9}, {0, 0}},
+	}
+
+	chunks, err := chunkSpans(text, tokens, 5, 1)
+	if err != nil {
+		t.Fatalf("Failed to plan chunks: %v", err)
+	}
+	want := []Chunk{{Start: 0, End: 5, Tokens: 3}, {Start: 4, End: 9, Tokens: 3}}
+	if len(chunks) != len(want) {
+		t.Fatalf("Expected %d chunks, got %+v", len(want), chunks)
+	}
+	for i := range want {
+		if c := chunks[i]; c.Start != want[i].Start || c.End != want[i].End || c.Tokens != want[i].Tokens {
+			t.Errorf("Chunk %d: expected %+v, got %+v", i, want[i], chunks[i])
+		}
+	}
+
+	if chunks, err := chunkSpans(text, tokens, 512, 0); err != nil || len(chunks) != 1 || chunks[0].End != 9 {
+		t.Errorf("Expected a single chunk for a short text, got %+v, %v", chunks, err)
+	}
+	if chunks, err := chunkSpans("", Tokens{Offsets: [][2]int{{0, 0}, {0, 0}}}, 512, 0); err != nil || len(chunks) != 1 {
+		t.Errorf("Expected one chunk for an empty text, got %+v, %v", chunks, err)
+	}
+	if _, err := chunkSpans(text, tokens, 4, 2); err == nil {
+		t.Error("Expected an error for an overlap that fills the chunk")
+	}
+}
+
+func TestChunkSpans_WordBoundaries(t *testing.T) {
+	// A chunk of 3 content tokens would end inside "embedding"
+	text := "a embedding b"
+	tokens := Tokens{
+		Tokens:  []string{"[CLS]", "a", "em", "##bed", "##ding", "b", "[SEP]"},
+		Offsets: [][2]int{{0, 0}, {0, 1}, {2, 4}, {4, 7}, {7, 11}, {12, 13}, {0, 0}},
+	}
+	chunks, err := chunkSpans(text, tokens, 5, 0)
+	if err != nil {
+		t.Fatalf("Failed to plan chunks: %v", err)
+	}
+	want := []string{"a", "embedding", "b"}
+	if len(chunks) != len(want) {
+		t.Fatalf("Expected chunks %q, got %+v", want, chunks)
+	}
+	for i, c := range chunks {
+		if got := text[c.Start:c.End]; got != want[i] {
+			t.Errorf("Chunk %d: expected %q, got %q", i, want[i], got)
+		}
+	}
+
+	// An overlap starting inside a word is extended to the word start
+	if chunks, err := chunkSpans(text, tokens, 6, 1); err != nil || len(chunks) != 2 || text[chunks[1].Start:chunks[1].End] != "embedding b" {
+		t.Errorf("Expected the second chunk to start at a word, got %+v, %v", chunks, err)
+	}
+
+	// SentencePiece marks word starts instead of continuations
+	text = "unbelievable day"
+	tokens = Tokens{
+		Tokens:  []string{"<s>", "▁un", "believ", "able", "▁day", "</s>"},
+		Offsets: [][2]int{{0, 0}, {0, 2}, {2, 8}, {8, 12}, {13, 16}, {0, 0}},
+	}
+	if chunks, err := chunkSpans(text, tokens, 4, 0); err != nil || len(chunks) != 2 || chunks[0].End != 8 {
+		t.Errorf("Expected a word longer than a chunk to be cut inside, got %+v, %v", chunks, err)
+	}
+	if chunks, err := chunkSpans(text, tokens, 5, 0); err != nil || len(chunks) != 2 || text[chunks[0].Start:chunks[0].End] != "unbelievable" {
+		t.Errorf("Expected chunks to end between words, got %+v, %v", chunks, err)
+	}
+}
+
+func TestPoolChunks(t *testing.T) {
+	chunks := []Chunk{
+		{Tokens: 3, Embedding: []float32{1, 0}},
+		{Tokens: 1, Embedding: []float32{0, 1}},
+	}
+
+	mean := poolChunks(chunks, ChunkPoolingMean)
+	if math.Abs(float64(mean[0]-mean[1])) > 1e-6 {
+		t.Errorf("Expected equal weights with mean pooling, got %v", mean)
+	}
+	weighted := poolChunks(chunks, ChunkPoolingWeighted)
+	if math.Abs(float64(weighted[0]-3*weighted[1])) > 1e-6 {
+		t.Errorf("Expected weights by token count, got %v", weighted)
+	}
+	if norm := weighted[0]*weighted[0] + weighted[1]*weighted[1]; math.Abs(float64(norm-1)) > 1e-6 {
+		t.Errorf("Expected a unit vector, got norm %f", norm)
+	}
+}
+
+func TestTextEmbedding_EmbedChunks(t *testing.T) {
+	model, err := NewTextEmbedding("BGESmallENV15")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer model.Close()
+
+	// Long words split into several tokens, so chunk boundaries can fall
+	// inside them
+	long := strings.Repeat("Antidisestablishmentarianism tokenizes unpredictably. ", 20)
+	docs := []string{"A short document.", long}
+	chunks, err := model.EmbedChunks(context.Background(), docs, WithChunkSize(64), WithChunkOverlap(8))
+	if err != nil {
+		t.Fatalf("Failed to embed chunks: %v", err)
+	}
+	if len(chunks[0]) != 1 || len(chunks[1]) < 3 {
+		t.Fatalf("Expected one chunk for the short document and several for the long one, got %d and %d", len(chunks[0]), len(chunks[1]))
+	}
+	for _, c := range chunks[1] {
+		if len(c.Embedding) != model.Dimension() || c.Tokens > 62 || c.End <= c.Start {
+			t.Errorf("Unexpected chunk %d..%d with %d tokens", c.Start, c.End, c.Tokens)
+		}
+	}
+	if last := chunks[1][len(chunks[1])-1]; strings.TrimSpace(long[last.End:]) != "" {
+		t.Error("Expected the chunks to cover the whole document")
+	}
+	texts := make([]string, len(chunks[1]))
+	for i, c := range chunks[1] {
+		texts[i] = long[c.Start:c.End]
+	}
+	counts, err := model.CountTokens(texts)
+	if err != nil {
+		t.Fatalf("Failed to count tokens: %v", err)
+	}
+	for i, n := range counts {
+		if n > 64 {
+			t.Errorf("Expected chunk %d to fit the chunk size, it has %d tokens", i, n)
+		}
+	}
+
+	pooled, err := model.EmbedDocuments(context.Background(), docs, WithChunkSize(64), WithChunkPooling(ChunkPoolingWeighted))
+	if err != nil {
+		t.Fatalf("Failed to embed documents: %v", err)
+	}
+	if len(pooled) != 2 || len(pooled[1]) != model.Dimension() {
+		t.Fatalf("Expected one vector per document, got %d", len(pooled))
+	}
+}
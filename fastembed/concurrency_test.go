@@ -0,0 +1,106 @@
+package fastembed
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestClosedModel(t *testing.T) {
+	te := &TextEmbedding{}
+	te.Close()
+	te.Close()
+	if _, err := te.Embed([]string{"Hello"}, 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from TextEmbedding, got %v", err)
+	}
+	if _, err := te.Tokenize("Hello"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from TextEmbedding.Tokenize, got %v", err)
+	}
+
+	ste := &SparseTextEmbedding{}
+	ste.Close()
+	if _, err := ste.Embed([]string{"Hello"}, 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from SparseTextEmbedding, got %v", err)
+	}
+
+	ie := &ImageEmbedding{}
+	ie.Close()
+	if _, err := ie.Embed([]string{"image.png"}, 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from ImageEmbedding, got %v", err)
+	}
+
+	tr := &TextRerank{}
+	tr.Close()
+	if _, err := tr.Rerank("query", []string{"document"}, false, 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from TextRerank, got %v", err)
+	}
+}
+
+func TestTextEmbedding_Concurrent(t *testing.T) {
+	te, err := NewTextEmbedding("")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer te.Close()
+
+	want, err := te.Embed([]string{"Hello, World!"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed texts: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				got, err := te.Embed([]string{"Hello, World!", "This is a test."}, 1)
+				if err != nil {
+					t.Errorf("Failed to embed texts: %v", err)
+					return
+				}
+				for k := range want[0] {
+					if got[0][k] != want[0][k] {
+						t.Errorf("Concurrent embedding differs at %d", k)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestTextEmbedding_CloseDuringEmbed(t *testing.T) {
+	te, err := NewTextEmbedding("")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+
+	texts := make([]string, 64)
+	for i := range texts {
+		texts[i] = "This is a test sentence."
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				_, err := te.Embed(texts, 8)
+				if errors.Is(err, ErrClosed) {
+					return
+				}
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	te.Close()
+	wg.Wait()
+	te.Close()
+}
@@ -0,0 +1,121 @@
+package fastembed
+
+/*
+#include "fastembed.h"
+*/
+import "C"
+import "context"
+
+// CallOption configures a single Embed or Rerank call
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	batchSize       int
+	returnDocuments bool
+	concurrency     int
+	ordered         bool
+	bufferSize      int
+	skipInvalid     bool
+	reportTruncated bool
+	chunkSize       int
+	chunkOverlap    int
+	chunkPooling    ChunkPooling
+}
+
+// WithBatchSize sets how many inputs are processed per batch. Cancellation is
+// checked between batches. Zero uses the fastembed-rs default of 256.
+func WithBatchSize(batchSize int) CallOption {
+	return func(o *callOptions) {
+		o.batchSize = batchSize
+	}
+}
+
+// WithConcurrency sets how many batches a stream embeds at once. The
+// default is 1.
+func WithConcurrency(n int) CallOption {
+	return func(o *callOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithOrdered sets whether a stream yields results in input order, which is
+// the default. Unordered streams yield each batch as soon as it is done.
+func WithOrdered(ordered bool) CallOption {
+	return func(o *callOptions) {
+		o.ordered = ordered
+	}
+}
+
+// WithBufferSize sets how many results a stream buffers before it stops
+// reading input until the consumer catches up. The default is 0, so at most
+// the batches being embedded are held in memory.
+func WithBufferSize(n int) CallOption {
+	return func(o *callOptions) {
+		o.bufferSize = n
+	}
+}
+
+// WithReportTruncated fills the Truncated fields of results with which
+// inputs were cut to the model's max length. It is honored by
+// TextEmbedding.EmbedDense and EmbedInto, SparseTextEmbedding.EmbedContext
+// and EmbedStream, and TextRerank.RerankContext. Methods returning plain
+// [][]float32 vectors, such as TextEmbedding.EmbedContext, EmbedQuery,
+// EmbedStream and EmbedDocuments, have no field to report it in and ignore
+// the option. It tokenizes the inputs a second time, so it is off by
+// default.
+func WithReportTruncated(report bool) CallOption {
+	return func(o *callOptions) {
+		o.reportTruncated = report
+	}
+}
+
+// WithReturnDocuments makes Rerank include the document text in its results
+func WithReturnDocuments(returnDocuments bool) CallOption {
+	return func(o *callOptions) {
+		o.returnDocuments = returnDocuments
+	}
+}
+
+func newCallOptions(opts []CallOption) *callOptions {
+	o := &callOptions{ordered: true}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// newCancelToken returns a cancel token that is cancelled once ctx is done,
+// or nil if ctx can never be cancelled. The returned function releases the
+// token and must be called after the call using it has returned.
+func newCancelToken(ctx context.Context) (*C.FastEmbedCancelToken, func()) {
+	if ctx.Done() == nil {
+		return nil, func() {}
+	}
+
+	token := C.fastembed_cancel_token_new()
+	cancelled := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		C.fastembed_cancel_token_cancel(token)
+		close(cancelled)
+	})
+	return token, func() {
+		if !stop() {
+			// The cancel function already started; wait until it no
+			// longer uses the token
+			<-cancelled
+		}
+		C.fastembed_cancel_token_free(token)
+	}
+}
+
+// newCallError converts the error of a cancellable call on model, returning
+// ctx.Err() if the call stopped because ctx was cancelled
+func newCallError(ctx context.Context, model string, cErr *C.FastEmbedError) error {
+	if cErr != nil && cErr.code == C.FASTEMBED_ERROR_CANCELLED && ctx.Err() != nil {
+		C.fastembed_error_free(cErr)
+		return ctx.Err()
+	}
+	return newModelError(model, cErr)
+}
@@ -0,0 +1,120 @@
+package fastembed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewCancelToken(t *testing.T) {
+	token, free := newCancelToken(context.Background())
+	if token != nil {
+		t.Error("Expected no cancel token for a context that is never done")
+	}
+	free()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, free = newCancelToken(ctx)
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		free()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Releasing a cancelled token did not return")
+	}
+}
+
+func TestTextEmbedding_EmbedContextCancelled(t *testing.T) {
+	te, err := NewTextEmbedding("")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer te.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := te.EmbedContext(ctx, []string{"Hello, World!"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestTextEmbedding_EmbedContextDeadline(t *testing.T) {
+	te, err := NewTextEmbedding("")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer te.Close()
+
+	texts := make([]string, 2000)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("This is test sentence number %d.", i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = te.EmbedContext(ctx, texts, WithBatchSize(1))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected embedding to stop soon after the deadline, took %v", elapsed)
+	}
+
+	// The model stays usable after a cancelled call
+	embeddings, err := te.EmbedContext(context.Background(), texts[:3], WithBatchSize(2))
+	if err != nil {
+		t.Fatalf("Failed to embed texts: %v", err)
+	}
+	if len(embeddings) != 3 {
+		t.Errorf("Expected 3 embeddings, got %d", len(embeddings))
+	}
+}
+
+func TestTextRerank_RerankContextBatches(t *testing.T) {
+	tr, err := NewTextRerank("")
+	if err != nil {
+		t.Fatalf("Failed to create text rerank: %v", err)
+	}
+	defer tr.Close()
+
+	query := "What is the capital of France?"
+	documents := []string{
+		"Berlin is the capital of Germany.",
+		"Paris is the capital of France.",
+		"The Eiffel Tower is in Paris.",
+		"Bananas are yellow.",
+		"France is a country in Europe.",
+	}
+	results, err := tr.RerankContext(context.Background(), query, documents,
+		WithBatchSize(2), WithReturnDocuments(true))
+	if err != nil {
+		t.Fatalf("Failed to rerank documents: %v", err)
+	}
+	if len(results) != len(documents) {
+		t.Fatalf("Expected %d results, got %d", len(documents), len(results))
+	}
+
+	seen := make(map[int]bool)
+	for i, r := range results {
+		if i > 0 && r.Score > results[i-1].Score {
+			t.Errorf("Results not sorted by score at %d", i)
+		}
+		if r.Document != documents[r.Index] {
+			t.Errorf("Result %d: document %q does not match index %d", i, r.Document, r.Index)
+		}
+		seen[r.Index] = true
+	}
+	if len(seen) != len(documents) {
+		t.Errorf("Expected every document index once, got %v", seen)
+	}
+	if results[0].Index != 1 {
+		t.Errorf("Expected the Paris document first, got %d", results[0].Index)
+	}
+}
@@ -0,0 +1,195 @@
+package fastembed
+
+/*
+#include "fastembed.h"
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"context"
+	"fmt"
+	"unsafe"
+)
+
+// DenseEmbeddings holds dense embeddings row after row in one contiguous
+// buffer
+type DenseEmbeddings struct {
+	Data      []float32 // Len() * Dim floats
+	Dim       int       // Embedding dimension and stride between rows
+	Truncated []bool    // Whether each text was cut to the model's max length, with WithReportTruncated
+}
+
+// Len returns the number of embeddings
+func (d DenseEmbeddings) Len() int {
+	if d.Dim == 0 {
+		return 0
+	}
+	return len(d.Data) / d.Dim
+}
+
+// Row returns embedding i. The row shares memory with Data.
+func (d DenseEmbeddings) Row(i int) []float32 {
+	start, end := i*d.Dim, (i+1)*d.Dim
+	return d.Data[start:end:end]
+}
+
+// Rows returns all embeddings as rows sharing memory with Data
+func (d DenseEmbeddings) Rows() [][]float32 {
+	rows := make([][]float32, d.Len())
+	for i := range rows {
+		rows[i] = d.Row(i)
+	}
+	return rows
+}
+
+// EmbedDense generates embeddings for the given texts into one contiguous
+// buffer
+func (te *TextEmbedding) EmbedDense(ctx context.Context, texts []string, opts ...CallOption) (DenseEmbeddings, error) {
+	return te.EmbedInto(ctx, make([]float32, len(texts)*te.Dimension()), texts, opts...)
+}
+
+// EmbedInto generates embeddings for the given texts into dst, which must
+// hold len(texts) * Dimension() floats, and returns them as a view of dst.
+// Reusing dst across calls avoids allocating result memory.
+func (te *TextEmbedding) EmbedInto(ctx context.Context, dst []float32, texts []string, opts ...CallOption) (DenseEmbeddings, error) {
+	dense, _, err := te.embedInto(ctx, dst, texts, newCallOptions(opts))
+	return dense, err
+}
+
+// embedInto implements EmbedInto and also returns the positions of the
+// embedded texts if invalid ones were skipped
+func (te *TextEmbedding) embedInto(ctx context.Context, dst []float32, texts []string, o *callOptions) (DenseEmbeddings, []int, error) {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+	if te.handle == nil {
+		return DenseEmbeddings{}, nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return DenseEmbeddings{}, nil, err
+	}
+	dim, err := checkDenseOutput(dst, len(texts), te.dimension)
+	if err != nil {
+		return DenseEmbeddings{}, nil, err
+	}
+	valid, kept, err := validateInputs(texts, o.skipInvalid, checkText)
+	if err != nil {
+		return DenseEmbeddings{}, nil, err
+	}
+
+	var truncated []bool
+	if o.reportTruncated {
+		truncated = make([]bool, len(valid))
+	}
+
+	if len(valid) > 0 {
+		packed := packStrings(valid)
+
+		cancel, freeCancel := newCancelToken(ctx)
+		defer freeCancel()
+
+		var cErr *C.FastEmbedError
+		ok := C.fastembed_text_embedding_embed_into(
+			te.handle,
+			packed.dataPtr(),
+			packed.offsetsPtr(),
+			packed.count(),
+			C.size_t(o.batchSize),
+			(*C.float)(unsafe.Pointer(&dst[0])),
+			C.size_t(len(dst)),
+			boolsPtr(truncated),
+			cancel,
+			&cErr,
+		)
+		if !ok {
+			return DenseEmbeddings{}, nil, newCallError(ctx, te.modelCode, cErr)
+		}
+	}
+	if kept != nil {
+		spreadRows(dst, dim, kept, len(texts))
+		if truncated != nil {
+			truncated = spread(truncated, kept, len(texts))
+		}
+	}
+
+	n := len(texts) * dim
+	return DenseEmbeddings{Data: dst[:n:n], Dim: dim, Truncated: truncated}, kept, nil
+}
+
+// EmbedDense generates embeddings for the given image paths into one
+// contiguous buffer
+func (ie *ImageEmbedding) EmbedDense(ctx context.Context, imagePaths []string, opts ...CallOption) (DenseEmbeddings, error) {
+	return ie.EmbedInto(ctx, make([]float32, len(imagePaths)*ie.Dimension()), imagePaths, opts...)
+}
+
+// EmbedInto generates embeddings for the given image paths into dst, which
+// must hold len(imagePaths) * Dimension() floats, and returns them as a view
+// of dst
+func (ie *ImageEmbedding) EmbedInto(ctx context.Context, dst []float32, imagePaths []string, opts ...CallOption) (DenseEmbeddings, error) {
+	dense, _, err := ie.embedInto(ctx, dst, imagePaths, newCallOptions(opts))
+	return dense, err
+}
+
+// embedInto implements EmbedInto and also returns the positions of the
+// embedded image paths if invalid ones were skipped
+func (ie *ImageEmbedding) embedInto(ctx context.Context, dst []float32, imagePaths []string, o *callOptions) (DenseEmbeddings, []int, error) {
+	ie.mu.RLock()
+	defer ie.mu.RUnlock()
+	if ie.handle == nil {
+		return DenseEmbeddings{}, nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return DenseEmbeddings{}, nil, err
+	}
+	dim, err := checkDenseOutput(dst, len(imagePaths), ie.dimension)
+	if err != nil {
+		return DenseEmbeddings{}, nil, err
+	}
+	valid, kept, err := validateInputs(imagePaths, o.skipInvalid, checkImagePath)
+	if err != nil {
+		return DenseEmbeddings{}, nil, err
+	}
+
+	if len(valid) > 0 {
+		packed := packStrings(valid)
+
+		cancel, freeCancel := newCancelToken(ctx)
+		defer freeCancel()
+
+		var cErr *C.FastEmbedError
+		ok := C.fastembed_image_embedding_embed_into(
+			ie.handle,
+			packed.dataPtr(),
+			packed.offsetsPtr(),
+			packed.count(),
+			C.size_t(o.batchSize),
+			(*C.float)(unsafe.Pointer(&dst[0])),
+			C.size_t(len(dst)),
+			cancel,
+			&cErr,
+		)
+		if !ok {
+			return DenseEmbeddings{}, nil, newCallError(ctx, ie.modelCode, cErr)
+		}
+	}
+	if kept != nil {
+		spreadRows(dst, dim, kept, len(imagePaths))
+	}
+
+	n := len(imagePaths) * dim
+	return DenseEmbeddings{Data: dst[:n:n], Dim: dim}, kept, nil
+}
+
+// checkDenseOutput checks that dst holds rows embeddings of the model
+// dimension and returns the dimension
+func checkDenseOutput(dst []float32, rows, dim int) (int, error) {
+	if dim == 0 {
+		return 0, &Error{message: "embedding dimension of the model is unknown", code: ErrorInvalidInput}
+	}
+	if need := rows * dim; len(dst) < need {
+		return 0, &Error{
+			message: fmt.Sprintf("output buffer holds %d floats, %d needed for %d embeddings of dimension %d", len(dst), need, rows, dim),
+			code:    ErrorInvalidInput,
+		}
+	}
+	return dim, nil
+}
@@ -0,0 +1,78 @@
+package fastembed
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDenseEmbeddings_Rows(t *testing.T) {
+	d := DenseEmbeddings{Data: []float32{1, 2, 3, 4, 5, 6}, Dim: 3}
+	if d.Len() != 2 {
+		t.Fatalf("Expected 2 rows, got %d", d.Len())
+	}
+	if row := d.Row(1); len(row) != 3 || row[0] != 4 || cap(row) != 3 {
+		t.Errorf("Unexpected row 1: %v (cap %d)", row, cap(row))
+	}
+
+	rows := d.Rows()
+	rows[0][0] = 10
+	if d.Data[0] != 10 {
+		t.Error("Expected rows to share memory with Data")
+	}
+	rows[0] = append(rows[0], 99)
+	if d.Data[3] != 4 {
+		t.Error("Appending to a row must not overwrite the next row")
+	}
+
+	if (DenseEmbeddings{}).Len() != 0 {
+		t.Error("Expected an empty result to have no rows")
+	}
+}
+
+func TestCheckDenseOutput(t *testing.T) {
+	if _, err := checkDenseOutput(make([]float32, 5), 2, 3); err == nil {
+		t.Error("Expected an error for a short buffer")
+	}
+	if _, err := checkDenseOutput(make([]float32, 6), 2, 0); err == nil {
+		t.Error("Expected an error for an unknown dimension")
+	}
+	if dim, err := checkDenseOutput(make([]float32, 8), 2, 3); err != nil || dim != 3 {
+		t.Errorf("Expected dimension 3 for a large enough buffer, got %d, %v", dim, err)
+	}
+}
+
+func TestTextEmbedding_EmbedDense(t *testing.T) {
+	model, err := NewTextEmbedding("")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer model.Close()
+
+	texts := []string{"Hello, World!", "This is a test.", "Contiguous results."}
+	dense, err := model.EmbedDense(context.Background(), texts, WithBatchSize(2))
+	if err != nil {
+		t.Fatalf("Failed to embed: %v", err)
+	}
+	if dense.Len() != len(texts) || dense.Dim != model.Dimension() {
+		t.Fatalf("Expected %d rows of dimension %d, got %d of %d", len(texts), model.Dimension(), dense.Len(), dense.Dim)
+	}
+
+	// Reuse a larger buffer; the result is a view of it
+	buf := make([]float32, 10*model.Dimension())
+	into, err := model.EmbedInto(context.Background(), buf, texts)
+	if err != nil {
+		t.Fatalf("Failed to embed into buffer: %v", err)
+	}
+	if &into.Data[0] != &buf[0] {
+		t.Error("Expected EmbedInto to fill the given buffer")
+	}
+	for i, v := range dense.Data {
+		if diff := v - into.Data[i]; diff > 1e-4 || diff < -1e-4 {
+			t.Fatalf("EmbedDense and EmbedInto differ at %d: %f vs %f", i, v, into.Data[i])
+		}
+	}
+
+	if _, err := model.EmbedInto(context.Background(), buf[:1], texts); err == nil {
+		t.Error("Expected an error for a short buffer")
+	}
+}
@@ -0,0 +1,104 @@
+package fastembed
+
+/*
+#include "fastembed.h"
+*/
+import "C"
+
+// InitOptions reports the effective options a model was created with
+type InitOptions struct {
+	CacheDir             string // Model cache directory, "" for models loaded from files or memory
+	MaxLength            int    // Maximum input sequence length in tokens, 0 for image models
+	ShowDownloadProgress bool
+	Offline              bool
+	Endpoint             string // Download mirror, "" for Hugging Face
+	QueryPrefix          string // Prefix EmbedQuery adds to queries
+	PassagePrefix        string // Prefix EmbedPassage adds to passages
+}
+
+// modelDetails describes the model behind a handle. It is embedded in every
+// model type to provide their introspection methods.
+type modelDetails struct {
+	modelCode string
+	dimension int
+	options   InitOptions
+}
+
+// newModelDetails combines the details reported by the Rust library with the
+// options the model was created with
+func newModelDetails(cDetails *C.FastEmbedModelDetails, o *initOptions) modelDetails {
+	showDownloadProgress := true
+	if o.showDownloadProgress != nil {
+		showDownloadProgress = *o.showDownloadProgress
+	}
+	queryPrefix := C.GoString(cDetails.query_prefix)
+	if o.queryPrefix != nil {
+		queryPrefix = *o.queryPrefix
+	}
+	passagePrefix := C.GoString(cDetails.passage_prefix)
+	if o.passagePrefix != nil {
+		passagePrefix = *o.passagePrefix
+	}
+	return modelDetails{
+		modelCode: C.GoString(cDetails.model_code),
+		dimension: int(cDetails.dim),
+		options: InitOptions{
+			CacheDir:             C.GoString(cDetails.cache_dir),
+			MaxLength:            int(cDetails.max_length),
+			ShowDownloadProgress: showDownloadProgress,
+			Offline:              o.offline,
+			Endpoint:             o.endpoint,
+			QueryPrefix:          queryPrefix,
+			PassagePrefix:        passagePrefix,
+		},
+	}
+}
+
+func textEmbeddingDetails(handle *C.TextEmbeddingHandle, o *initOptions) modelDetails {
+	var cDetails C.FastEmbedModelDetails
+	C.fastembed_text_embedding_details(handle, &cDetails)
+	return newModelDetails(&cDetails, o)
+}
+
+func sparseTextEmbeddingDetails(handle *C.SparseTextEmbeddingHandle, o *initOptions) modelDetails {
+	var cDetails C.FastEmbedModelDetails
+	C.fastembed_sparse_text_embedding_details(handle, &cDetails)
+	return newModelDetails(&cDetails, o)
+}
+
+func imageEmbeddingDetails(handle *C.ImageEmbeddingHandle, o *initOptions) modelDetails {
+	var cDetails C.FastEmbedModelDetails
+	C.fastembed_image_embedding_details(handle, &cDetails)
+	return newModelDetails(&cDetails, o)
+}
+
+func textRerankDetails(handle *C.TextRerankHandle, o *initOptions) modelDetails {
+	var cDetails C.FastEmbedModelDetails
+	C.fastembed_text_rerank_details(handle, &cDetails)
+	return newModelDetails(&cDetails, o)
+}
+
+// ModelCode returns the resolved code of the loaded model, e.g.
+// "Xenova/bge-small-en-v1.5". It is empty for models loaded from files or
+// memory.
+func (d modelDetails) ModelCode() string {
+	return d.modelCode
+}
+
+// Dimension returns the length of the vectors the model produces. For sparse
+// models it is the vocabulary size; it is 0 for rerankers and for
+// user-defined sparse models.
+func (d modelDetails) Dimension() int {
+	return d.dimension
+}
+
+// MaxLength returns the maximum input sequence length in tokens. Longer
+// inputs are truncated. It is 0 for image models.
+func (d modelDetails) MaxLength() int {
+	return d.options.MaxLength
+}
+
+// Options returns the effective options the model was created with
+func (d modelDetails) Options() InitOptions {
+	return d.options
+}
@@ -0,0 +1,113 @@
+package fastembed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mirror is a minimal Hugging Face compatible file server that serves the
+// snapshot of one model repository
+type mirror struct {
+	mu       sync.Mutex
+	requests []string
+	auth     []string
+}
+
+// newMirror starts a mirror serving the files in snapshot as modelCode
+func newMirror(t *testing.T, modelCode, snapshot string) (*httptest.Server, *mirror) {
+	t.Helper()
+
+	m := &mirror{}
+	prefix := "/" + modelCode + "/resolve/main/"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.requests = append(m.requests, r.URL.Path)
+		m.auth = append(m.auth, r.Header.Get("Authorization"))
+		m.mu.Unlock()
+
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			http.NotFound(w, r)
+			return
+		}
+		path := filepath.Join(snapshot, filepath.FromSlash(strings.TrimPrefix(r.URL.Path, prefix)))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		sum := sha256.Sum256(data)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		w.Header().Set("X-Repo-Commit", "0123456789abcdef0123456789abcdef01234567")
+		f, err := os.Open(path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		http.ServeContent(w, r, filepath.Base(path), time.Time{}, f)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, m
+}
+
+func TestTextEmbedding_WithEndpoint(t *testing.T) {
+	// Download the model once from Hugging Face and serve it from a local mirror
+	upstream := t.TempDir()
+	te, err := NewTextEmbedding("Xenova/bge-small-en-v1.5", WithCacheDir(upstream))
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	te.Close()
+
+	srv, m := newMirror(t, "Xenova/bge-small-en-v1.5", snapshotDir(t, upstream, "Xenova/bge-small-en-v1.5"))
+
+	cacheDir := t.TempDir()
+	te, err = NewTextEmbedding("Xenova/bge-small-en-v1.5",
+		WithCacheDir(cacheDir),
+		WithEndpoint(srv.URL),
+		WithAuthToken("secret-token"),
+		WithShowDownloadProgress(false),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create text embedding from mirror: %v", err)
+	}
+	defer te.Close()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.requests) == 0 {
+		t.Fatal("Expected model files to be downloaded from the mirror")
+	}
+	for i, auth := range m.auth {
+		if auth != "Bearer secret-token" {
+			t.Errorf("Request %s: expected bearer token, got %q", m.requests[i], auth)
+		}
+	}
+
+	embeddings, err := te.Embed([]string{"Hello, World!"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed texts: %v", err)
+	}
+	if len(embeddings) != 1 {
+		t.Errorf("Expected 1 embedding, got %d", len(embeddings))
+	}
+}
+
+func TestTextEmbedding_WithEndpointUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	_, err := NewTextEmbedding("BGESmallENV15", WithCacheDir(t.TempDir()), WithEndpoint(srv.URL))
+	if err == nil {
+		t.Fatal("Expected an error for an unreachable endpoint")
+	}
+}
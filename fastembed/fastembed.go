@@ -8,36 +8,137 @@ package fastembed
 */
 import "C"
 import (
+	"context"
 	"fmt"
 	"runtime"
+	"sync"
 	"unsafe"
 )
 
-// Error represents a FastEmbed error
+// ErrorCode classifies an Error
+type ErrorCode int
+
+const (
+	ErrorGeneric        ErrorCode = C.FASTEMBED_ERROR_GENERIC
+	ErrorModelNotCached ErrorCode = C.FASTEMBED_ERROR_MODEL_NOT_CACHED
+	ErrorCancelled      ErrorCode = C.FASTEMBED_ERROR_CANCELLED
+	ErrorUnknownModel   ErrorCode = C.FASTEMBED_ERROR_UNKNOWN_MODEL
+	ErrorDownload       ErrorCode = C.FASTEMBED_ERROR_DOWNLOAD
+	ErrorInvalidInput   ErrorCode = C.FASTEMBED_ERROR_INVALID_INPUT
+	ErrorInference      ErrorCode = C.FASTEMBED_ERROR_INFERENCE
+	ErrorPanic          ErrorCode = C.FASTEMBED_ERROR_PANIC
+	ErrorClosed         ErrorCode = C.FASTEMBED_ERROR_CLOSED       // Set by the bindings, never by the C library
+	ErrorPoolTimeout    ErrorCode = C.FASTEMBED_ERROR_POOL_TIMEOUT // Set by the bindings, never by the C library
+)
+
+// Error represents a FastEmbed error. Use errors.Is with the Err sentinels
+// to test for a kind of error, or errors.As to read its details.
 type Error struct {
 	message string
+	code    ErrorCode
+	model   string // Model the error refers to, "" if none
+	input   int    // Index of the input the error refers to plus one, 0 if none
+}
+
+// ErrModelNotCached is returned in offline mode when model files are missing
+// from the cache. Use errors.Is to test for it; the error message lists the
+// missing files.
+var ErrModelNotCached = &Error{
+	message: "model files are not cached",
+	code:    ErrorModelNotCached,
 }
 
+// ErrUnknownModel is returned by constructors and Prefetch when the model
+// name matches no supported model. The error message lists the closest
+// matching model codes.
+var ErrUnknownModel = &Error{message: "unknown model", code: ErrorUnknownModel}
+
+// ErrDownload is returned when model files cannot be downloaded
+var ErrDownload = &Error{message: "model download failed", code: ErrorDownload}
+
+// ErrInvalidInput is matched by errors rejecting an input, including
+// *InputError
+var ErrInvalidInput = &Error{message: "invalid input", code: ErrorInvalidInput}
+
+// ErrInference is returned when the model fails to run on a batch
+var ErrInference = &Error{message: "inference failed", code: ErrorInference}
+
+// ErrPanic is returned when the Rust library panics during a call. The
+// panic is caught, so the process keeps running; the error message names
+// the failing function and summarizes the backtrace. It is also returned
+// when a WithProgressFunc function panics.
+var ErrPanic = &Error{message: "panic in fastembed library", code: ErrorPanic}
+
+// ErrClosed is returned by calls on a model after Close
+var ErrClosed = &Error{message: "model is closed", code: ErrorClosed}
+
 func (e *Error) Error() string {
 	return e.message
 }
 
+// Code returns the error code
+func (e *Error) Code() ErrorCode {
+	return e.code
+}
+
+// Model returns the model the error refers to: the requested model name for
+// constructor errors, the model code for errors of calls on a model. It is
+// empty if unknown or not applicable.
+func (e *Error) Model() string {
+	return e.model
+}
+
+// Index returns the position of the input the error refers to in the call,
+// or -1 if the error is not about a single input
+func (e *Error) Index() int {
+	return e.input - 1
+}
+
+// Is reports whether target is an *Error with the same error code, so that
+// errors.Is(err, ErrModelNotCached) works
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.code != 0 && t.code == e.code
+}
+
 // newError creates a new Error from a C error pointer
 func newError(cErr *C.FastEmbedError) error {
 	if cErr == nil {
 		return nil
 	}
 	defer C.fastembed_error_free(cErr)
-	return &Error{message: C.GoString(cErr.message)}
+	return &Error{
+		message: C.GoString(cErr.message),
+		code:    ErrorCode(cErr.code),
+		input:   int(cErr.index) + 1,
+	}
 }
 
-// TextEmbedding represents a text embedding model
+// newModelError creates a new Error from a C error pointer and records the
+// model it refers to
+func newModelError(model string, cErr *C.FastEmbedError) error {
+	err := newError(cErr)
+	if e, ok := err.(*Error); ok {
+		e.model = model
+	}
+	return err
+}
+
+// TextEmbedding represents a text embedding model. It is safe for concurrent use;
+// concurrent calls take turns running batches on the model.
 type TextEmbedding struct {
+	mu     sync.RWMutex // held for reading by calls, for writing by Close
 	handle *C.TextEmbeddingHandle
+	modelDetails
 }
 
-// NewTextEmbedding creates a new text embedding model instance
-func NewTextEmbedding(modelName string) (*TextEmbedding, error) {
+// NewTextEmbedding creates a new text embedding model instance.
+// The model name can be any model code returned by ListTextEmbeddingModels
+// (e.g. "Xenova/bge-small-en-v1.5") or a fastembed-rs enum name
+// (e.g. "BGESmallENV15"). An empty name selects the default model. Unknown
+// names return an error that lists the closest matching model codes.
+// Options such as WithCacheDir and WithMaxLength customize initialization.
+func NewTextEmbedding(modelName string, opts ...Option) (*TextEmbedding, error) {
 	var cErr *C.FastEmbedError
 	var cModelName *C.char
 	if modelName != "" {
@@ -45,12 +146,20 @@ func NewTextEmbedding(modelName string) (*TextEmbedding, error) {
 		defer C.free(unsafe.Pointer(cModelName))
 	}
 
-	handle := C.fastembed_text_embedding_new(cModelName, &cErr)
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	handle := C.fastembed_text_embedding_new(cModelName, cOpts, &cErr)
+	if err := o.progressErr(modelName, cErr); err != nil {
+		C.fastembed_text_embedding_free(handle)
+		return nil, err
+	}
 	if handle == nil {
-		return nil, newError(cErr)
+		return nil, newModelError(modelName, cErr)
 	}
 
-	te := &TextEmbedding{handle: handle}
+	te := &TextEmbedding{handle: handle, modelDetails: textEmbeddingDetails(handle, o)}
 	runtime.SetFinalizer(te, func(t *TextEmbedding) {
 		t.Close()
 	})
@@ -59,27 +168,59 @@ func NewTextEmbedding(modelName string) (*TextEmbedding, error) {
 
 // Embed generates embeddings for the given texts
 func (te *TextEmbedding) Embed(texts []string, batchSize int) ([][]float32, error) {
-	if te.handle == nil {
-		return nil, &Error{message: "TextEmbedding handle is nil"}
+	return te.EmbedContext(context.Background(), texts, WithBatchSize(batchSize))
+}
+
+// EmbedContext generates embeddings for the given texts. If ctx is cancelled
+// or its deadline passes, processing stops at the next batch boundary and
+// ctx.Err() is returned. Texts that are not valid UTF-8 fail the call with
+// an *InputError listing them, unless WithSkipInvalid is given. Empty input
+// returns an empty result.
+func (te *TextEmbedding) EmbedContext(ctx context.Context, texts []string, opts ...CallOption) ([][]float32, error) {
+	if te.dimension > 0 {
+		// Fill one contiguous buffer instead of copying vector by vector
+		dst := make([]float32, len(texts)*te.dimension)
+		dense, kept, err := te.embedInto(ctx, dst, texts, newCallOptions(opts))
+		if err != nil {
+			return nil, err
+		}
+		return dropSkipped(dense.Rows(), kept), nil
 	}
 
-	// Convert Go strings to C strings
-	cTexts := make([]*C.char, len(texts))
-	for i, text := range texts {
-		cTexts[i] = C.CString(text)
-		defer C.free(unsafe.Pointer(cTexts[i]))
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+	if te.handle == nil {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o := newCallOptions(opts)
+	valid, kept, err := validateInputs(texts, o.skipInvalid, checkText)
+	if err != nil {
+		return nil, err
 	}
+	if len(valid) == 0 {
+		return spread([][]float32{}, kept, len(texts)), nil
+	}
+
+	packed := packStrings(valid)
+
+	cancel, freeCancel := newCancelToken(ctx)
+	defer freeCancel()
 
 	var cErr *C.FastEmbedError
 	result := C.fastembed_text_embedding_embed(
 		te.handle,
-		(**C.char)(unsafe.Pointer(&cTexts[0])),
-		C.size_t(len(texts)),
-		C.size_t(batchSize),
+		packed.dataPtr(),
+		packed.offsetsPtr(),
+		packed.count(),
+		C.size_t(o.batchSize),
+		cancel,
 		&cErr,
 	)
 	if result == nil {
-		return nil, newError(cErr)
+		return nil, newCallError(ctx, te.modelCode, cErr)
 	}
 	defer C.fastembed_float_array_vec_free(result)
 
@@ -96,30 +237,40 @@ func (te *TextEmbedding) Embed(texts []string, batchSize int) ([][]float32, erro
 		embeddings[i] = embedding
 	}
 
-	return embeddings, nil
+	return spread(embeddings, kept, len(texts)), nil
 }
 
-// Close releases the resources associated with the text embedding model
+// Close releases the resources associated with the text embedding model.
+// It waits for in-flight calls to finish and is safe to call more than once;
+// calls made after Close return ErrClosed.
 func (te *TextEmbedding) Close() {
+	te.mu.Lock()
+	defer te.mu.Unlock()
 	if te.handle != nil {
 		C.fastembed_text_embedding_free(te.handle)
 		te.handle = nil
+		runtime.SetFinalizer(te, nil)
 	}
 }
 
 // SparseEmbedding represents a sparse embedding result
 type SparseEmbedding struct {
-	Indices []int
-	Values  []float32
+	Indices   []int
+	Values    []float32
+	Truncated bool // Whether the text was cut to the model's max length, with WithReportTruncated
 }
 
-// SparseTextEmbedding represents a sparse text embedding model
+// SparseTextEmbedding represents a sparse text embedding model. It is safe for concurrent use;
+// concurrent calls take turns running batches on the model.
 type SparseTextEmbedding struct {
+	mu     sync.RWMutex // held for reading by calls, for writing by Close
 	handle *C.SparseTextEmbeddingHandle
+	modelDetails
 }
 
-// NewSparseTextEmbedding creates a new sparse text embedding model instance
-func NewSparseTextEmbedding(modelName string) (*SparseTextEmbedding, error) {
+// NewSparseTextEmbedding creates a new sparse text embedding model instance.
+// Options such as WithCacheDir and WithMaxLength customize initialization.
+func NewSparseTextEmbedding(modelName string, opts ...Option) (*SparseTextEmbedding, error) {
 	var cErr *C.FastEmbedError
 	var cModelName *C.char
 	if modelName != "" {
@@ -127,12 +278,20 @@ func NewSparseTextEmbedding(modelName string) (*SparseTextEmbedding, error) {
 		defer C.free(unsafe.Pointer(cModelName))
 	}
 
-	handle := C.fastembed_sparse_text_embedding_new(cModelName, &cErr)
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	handle := C.fastembed_sparse_text_embedding_new(cModelName, cOpts, &cErr)
+	if err := o.progressErr(modelName, cErr); err != nil {
+		C.fastembed_sparse_text_embedding_free(handle)
+		return nil, err
+	}
 	if handle == nil {
-		return nil, newError(cErr)
+		return nil, newModelError(modelName, cErr)
 	}
 
-	ste := &SparseTextEmbedding{handle: handle}
+	ste := &SparseTextEmbedding{handle: handle, modelDetails: sparseTextEmbeddingDetails(handle, o)}
 	runtime.SetFinalizer(ste, func(s *SparseTextEmbedding) {
 		s.Close()
 	})
@@ -141,27 +300,52 @@ func NewSparseTextEmbedding(modelName string) (*SparseTextEmbedding, error) {
 
 // Embed generates sparse embeddings for the given texts
 func (ste *SparseTextEmbedding) Embed(texts []string, batchSize int) ([]SparseEmbedding, error) {
+	return ste.EmbedContext(context.Background(), texts, WithBatchSize(batchSize))
+}
+
+// EmbedContext generates sparse embeddings for the given texts, stopping at
+// the next batch boundary with ctx.Err() once ctx is done
+func (ste *SparseTextEmbedding) EmbedContext(ctx context.Context, texts []string, opts ...CallOption) ([]SparseEmbedding, error) {
+	ste.mu.RLock()
+	defer ste.mu.RUnlock()
 	if ste.handle == nil {
-		return nil, &Error{message: "SparseTextEmbedding handle is nil"}
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o := newCallOptions(opts)
+	valid, kept, err := validateInputs(texts, o.skipInvalid, checkText)
+	if err != nil {
+		return nil, err
 	}
+	if len(valid) == 0 {
+		return spread([]SparseEmbedding{}, kept, len(texts)), nil
+	}
+
+	packed := packStrings(valid)
 
-	// Convert Go strings to C strings
-	cTexts := make([]*C.char, len(texts))
-	for i, text := range texts {
-		cTexts[i] = C.CString(text)
-		defer C.free(unsafe.Pointer(cTexts[i]))
+	cancel, freeCancel := newCancelToken(ctx)
+	defer freeCancel()
+
+	var truncated []bool
+	if o.reportTruncated {
+		truncated = make([]bool, len(valid))
 	}
 
 	var cErr *C.FastEmbedError
 	result := C.fastembed_sparse_text_embedding_embed(
 		ste.handle,
-		(**C.char)(unsafe.Pointer(&cTexts[0])),
-		C.size_t(len(texts)),
-		C.size_t(batchSize),
+		packed.dataPtr(),
+		packed.offsetsPtr(),
+		packed.count(),
+		C.size_t(o.batchSize),
+		boolsPtr(truncated),
+		cancel,
 		&cErr,
 	)
 	if result == nil {
-		return nil, newError(cErr)
+		return nil, newCallError(ctx, ste.modelCode, cErr)
 	}
 	defer C.fastembed_sparse_embedding_vec_free(result)
 
@@ -182,29 +366,39 @@ func (ste *SparseTextEmbedding) Embed(texts []string, batchSize int) ([]SparseEm
 		}
 
 		embeddings[i] = SparseEmbedding{
-			Indices: indices,
-			Values:  values,
+			Indices:   indices,
+			Values:    values,
+			Truncated: truncated != nil && truncated[i],
 		}
 	}
 
-	return embeddings, nil
+	return spread(embeddings, kept, len(texts)), nil
 }
 
-// Close releases the resources associated with the sparse text embedding model
+// Close releases the resources associated with the sparse text embedding model.
+// It waits for in-flight calls to finish and is safe to call more than once;
+// calls made after Close return ErrClosed.
 func (ste *SparseTextEmbedding) Close() {
+	ste.mu.Lock()
+	defer ste.mu.Unlock()
 	if ste.handle != nil {
 		C.fastembed_sparse_text_embedding_free(ste.handle)
 		ste.handle = nil
+		runtime.SetFinalizer(ste, nil)
 	}
 }
 
-// ImageEmbedding represents an image embedding model
+// ImageEmbedding represents an image embedding model. It is safe for concurrent use;
+// concurrent calls take turns running batches on the model.
 type ImageEmbedding struct {
+	mu     sync.RWMutex // held for reading by calls, for writing by Close
 	handle *C.ImageEmbeddingHandle
+	modelDetails
 }
 
-// NewImageEmbedding creates a new image embedding model instance
-func NewImageEmbedding(modelName string) (*ImageEmbedding, error) {
+// NewImageEmbedding creates a new image embedding model instance.
+// Options such as WithCacheDir customize initialization.
+func NewImageEmbedding(modelName string, opts ...Option) (*ImageEmbedding, error) {
 	var cErr *C.FastEmbedError
 	var cModelName *C.char
 	if modelName != "" {
@@ -212,12 +406,20 @@ func NewImageEmbedding(modelName string) (*ImageEmbedding, error) {
 		defer C.free(unsafe.Pointer(cModelName))
 	}
 
-	handle := C.fastembed_image_embedding_new(cModelName, &cErr)
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	handle := C.fastembed_image_embedding_new(cModelName, cOpts, &cErr)
+	if err := o.progressErr(modelName, cErr); err != nil {
+		C.fastembed_image_embedding_free(handle)
+		return nil, err
+	}
 	if handle == nil {
-		return nil, newError(cErr)
+		return nil, newModelError(modelName, cErr)
 	}
 
-	ie := &ImageEmbedding{handle: handle}
+	ie := &ImageEmbedding{handle: handle, modelDetails: imageEmbeddingDetails(handle, o)}
 	runtime.SetFinalizer(ie, func(i *ImageEmbedding) {
 		i.Close()
 	})
@@ -226,27 +428,56 @@ func NewImageEmbedding(modelName string) (*ImageEmbedding, error) {
 
 // Embed generates embeddings for the given image paths
 func (ie *ImageEmbedding) Embed(imagePaths []string, batchSize int) ([][]float32, error) {
-	if ie.handle == nil {
-		return nil, &Error{message: "ImageEmbedding handle is nil"}
+	return ie.EmbedContext(context.Background(), imagePaths, WithBatchSize(batchSize))
+}
+
+// EmbedContext generates embeddings for the given image paths, stopping at
+// the next batch boundary with ctx.Err() once ctx is done
+func (ie *ImageEmbedding) EmbedContext(ctx context.Context, imagePaths []string, opts ...CallOption) ([][]float32, error) {
+	if ie.dimension > 0 {
+		// Fill one contiguous buffer instead of copying vector by vector
+		dst := make([]float32, len(imagePaths)*ie.dimension)
+		dense, kept, err := ie.embedInto(ctx, dst, imagePaths, newCallOptions(opts))
+		if err != nil {
+			return nil, err
+		}
+		return dropSkipped(dense.Rows(), kept), nil
 	}
 
-	// Convert Go strings to C strings
-	cPaths := make([]*C.char, len(imagePaths))
-	for i, path := range imagePaths {
-		cPaths[i] = C.CString(path)
-		defer C.free(unsafe.Pointer(cPaths[i]))
+	ie.mu.RLock()
+	defer ie.mu.RUnlock()
+	if ie.handle == nil {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o := newCallOptions(opts)
+	valid, kept, err := validateInputs(imagePaths, o.skipInvalid, checkImagePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(valid) == 0 {
+		return spread([][]float32{}, kept, len(imagePaths)), nil
 	}
 
+	packed := packStrings(valid)
+
+	cancel, freeCancel := newCancelToken(ctx)
+	defer freeCancel()
+
 	var cErr *C.FastEmbedError
 	result := C.fastembed_image_embedding_embed(
 		ie.handle,
-		(**C.char)(unsafe.Pointer(&cPaths[0])),
-		C.size_t(len(imagePaths)),
-		C.size_t(batchSize),
+		packed.dataPtr(),
+		packed.offsetsPtr(),
+		packed.count(),
+		C.size_t(o.batchSize),
+		cancel,
 		&cErr,
 	)
 	if result == nil {
-		return nil, newError(cErr)
+		return nil, newCallError(ctx, ie.modelCode, cErr)
 	}
 	defer C.fastembed_float_array_vec_free(result)
 
@@ -263,31 +494,41 @@ func (ie *ImageEmbedding) Embed(imagePaths []string, batchSize int) ([][]float32
 		embeddings[i] = embedding
 	}
 
-	return embeddings, nil
+	return spread(embeddings, kept, len(imagePaths)), nil
 }
 
-// Close releases the resources associated with the image embedding model
+// Close releases the resources associated with the image embedding model.
+// It waits for in-flight calls to finish and is safe to call more than once;
+// calls made after Close return ErrClosed.
 func (ie *ImageEmbedding) Close() {
+	ie.mu.Lock()
+	defer ie.mu.Unlock()
 	if ie.handle != nil {
 		C.fastembed_image_embedding_free(ie.handle)
 		ie.handle = nil
+		runtime.SetFinalizer(ie, nil)
 	}
 }
 
 // RerankResult represents a reranking result
 type RerankResult struct {
-	Index    int
-	Score    float32
-	Document string
+	Index     int
+	Score     float32
+	Document  string
+	Truncated bool // Whether the query and document were cut to the model's max length, with WithReportTruncated
 }
 
-// TextRerank represents a text reranking model
+// TextRerank represents a text reranking model. It is safe for concurrent use;
+// concurrent calls take turns running batches on the model.
 type TextRerank struct {
+	mu     sync.RWMutex // held for reading by calls, for writing by Close
 	handle *C.TextRerankHandle
+	modelDetails
 }
 
-// NewTextRerank creates a new text reranking model instance
-func NewTextRerank(modelName string) (*TextRerank, error) {
+// NewTextRerank creates a new text reranking model instance.
+// Options such as WithCacheDir and WithMaxLength customize initialization.
+func NewTextRerank(modelName string, opts ...Option) (*TextRerank, error) {
 	var cErr *C.FastEmbedError
 	var cModelName *C.char
 	if modelName != "" {
@@ -295,12 +536,20 @@ func NewTextRerank(modelName string) (*TextRerank, error) {
 		defer C.free(unsafe.Pointer(cModelName))
 	}
 
-	handle := C.fastembed_text_rerank_new(cModelName, &cErr)
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	handle := C.fastembed_text_rerank_new(cModelName, cOpts, &cErr)
+	if err := o.progressErr(modelName, cErr); err != nil {
+		C.fastembed_text_rerank_free(handle)
+		return nil, err
+	}
 	if handle == nil {
-		return nil, newError(cErr)
+		return nil, newModelError(modelName, cErr)
 	}
 
-	tr := &TextRerank{handle: handle}
+	tr := &TextRerank{handle: handle, modelDetails: textRerankDetails(handle, o)}
 	runtime.SetFinalizer(tr, func(t *TextRerank) {
 		t.Close()
 	})
@@ -309,32 +558,63 @@ func NewTextRerank(modelName string) (*TextRerank, error) {
 
 // Rerank reranks documents based on their relevance to the query
 func (tr *TextRerank) Rerank(query string, documents []string, returnDocuments bool, batchSize int) ([]RerankResult, error) {
+	return tr.RerankContext(context.Background(), query, documents,
+		WithReturnDocuments(returnDocuments), WithBatchSize(batchSize))
+}
+
+// RerankContext reranks documents based on their relevance to the query,
+// stopping at the next batch boundary with ctx.Err() once ctx is done.
+// Results are sorted by descending score.
+func (tr *TextRerank) RerankContext(ctx context.Context, query string, documents []string, opts ...CallOption) ([]RerankResult, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
 	if tr.handle == nil {
-		return nil, &Error{message: "TextRerank handle is nil"}
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+	o := newCallOptions(opts)
+	if reason := checkText(query); reason != "" {
+		return nil, &InputError{Inputs: []InvalidInput{{Index: -1, Reason: "query: " + reason}}}
+	}
+	valid, kept, err := validateInputs(documents, o.skipInvalid, checkText)
+	if err != nil {
+		return nil, err
+	}
+	if len(valid) == 0 {
+		return []RerankResult{}, nil
+	}
+
+	packedQuery := packStrings([]string{query})
+	packed := packStrings(valid)
 
-	cQuery := C.CString(query)
-	defer C.free(unsafe.Pointer(cQuery))
+	cancel, freeCancel := newCancelToken(ctx)
+	defer freeCancel()
 
-	// Convert Go strings to C strings
-	cDocs := make([]*C.char, len(documents))
-	for i, doc := range documents {
-		cDocs[i] = C.CString(doc)
-		defer C.free(unsafe.Pointer(cDocs[i]))
+	var truncated []bool
+	if o.reportTruncated {
+		truncated = make([]bool, len(valid))
 	}
 
 	var cErr *C.FastEmbedError
 	result := C.fastembed_text_rerank_rerank(
 		tr.handle,
-		cQuery,
-		(**C.char)(unsafe.Pointer(&cDocs[0])),
-		C.size_t(len(documents)),
-		C.bool(returnDocuments),
-		C.size_t(batchSize),
+		packedQuery.dataPtr(),
+		C.size_t(len(query)),
+		packed.dataPtr(),
+		packed.offsetsPtr(),
+		packed.count(),
+		// Documents are filled in from the input below, which keeps NUL
+		// bytes intact and saves copying them back
+		C.bool(false),
+		C.size_t(o.batchSize),
+		boolsPtr(truncated),
+		cancel,
 		&cErr,
 	)
 	if result == nil {
-		return nil, newError(cErr)
+		return nil, newCallError(ctx, tr.modelCode, cErr)
 	}
 	defer C.fastembed_rerank_result_vec_free(result)
 
@@ -343,23 +623,34 @@ func (tr *TextRerank) Rerank(query string, documents []string, returnDocuments b
 	cResults := (*[1 << 30]C.RerankResultC)(unsafe.Pointer(result.results))[:result.len:result.len]
 
 	for i, cResult := range cResults {
+		index := int(cResult.index)
+		pairTruncated := truncated != nil && truncated[index]
+		if kept != nil {
+			index = kept[index]
+		}
 		results[i] = RerankResult{
-			Index: int(cResult.index),
-			Score: float32(cResult.score),
+			Index:     index,
+			Score:     float32(cResult.score),
+			Truncated: pairTruncated,
 		}
-		if cResult.document != nil {
-			results[i].Document = C.GoString(cResult.document)
+		if o.returnDocuments {
+			results[i].Document = documents[index]
 		}
 	}
 
 	return results, nil
 }
 
-// Close releases the resources associated with the text reranking model
+// Close releases the resources associated with the text reranking model.
+// It waits for in-flight calls to finish and is safe to call more than once;
+// calls made after Close return ErrClosed.
 func (tr *TextRerank) Close() {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
 	if tr.handle != nil {
 		C.fastembed_text_rerank_free(tr.handle)
 		tr.handle = nil
+		runtime.SetFinalizer(tr, nil)
 	}
 }
 
@@ -370,14 +661,21 @@ func (e *Error) String() string {
 
 // ModelInfo represents information about a supported model
 type ModelInfo struct {
-	ModelCode   string
-	Description string
-	Dimension   int
+	ModelCode     string
+	Description   string
+	Dimension     int     // Embedding dimension, 0 for rerankers
+	Pooling       Pooling // Default pooling, PoolingDefault when not applicable
+	MaxTokens     int     // Maximum input tokens the model supports, 0 for image models
+	QueryPrefix   string  // Recommended prefix for search queries
+	PassagePrefix string  // Recommended prefix for documents and passages
+	ModelFile     string  // ONNX file within the model repository
+	ApproxSize    int64   // Download size of the ONNX files in bytes, 0 if neither cached in the listed cache dir nor on the model card
+	Quantized     bool    // Whether the model is a quantized variant
+	Multilingual  bool    // Whether the model was trained on many languages
 }
 
-// ListTextEmbeddingModels returns a list of all supported text embedding models
-func ListTextEmbeddingModels() []ModelInfo {
-	cVec := C.fastembed_text_embedding_list_supported_models()
+// newModelInfos converts and frees a C model info vector
+func newModelInfos(cVec *C.ModelInfoVec) []ModelInfo {
 	if cVec == nil {
 		return nil
 	}
@@ -388,77 +686,65 @@ func ListTextEmbeddingModels() []ModelInfo {
 
 	for i, model := range models {
 		result[i] = ModelInfo{
-			ModelCode:   C.GoString(model.model_code),
-			Description: C.GoString(model.description),
-			Dimension:   int(model.dim),
+			ModelCode:     C.GoString(model.model_code),
+			Description:   C.GoString(model.description),
+			Dimension:     int(model.dim),
+			Pooling:       Pooling(model.pooling),
+			MaxTokens:     int(model.max_tokens),
+			QueryPrefix:   C.GoString(model.query_prefix),
+			PassagePrefix: C.GoString(model.passage_prefix),
+			ModelFile:     C.GoString(model.model_file),
+			ApproxSize:    int64(model.approx_size),
+			Quantized:     bool(model.quantized),
+			Multilingual:  bool(model.multilingual),
 		}
 	}
 
 	return result
 }
 
-// ListSparseTextEmbeddingModels returns a list of all supported sparse text embedding models
-func ListSparseTextEmbeddingModels() []ModelInfo {
-	cVec := C.fastembed_sparse_text_embedding_list_supported_models()
-	if cVec == nil {
-		return nil
+// listingCacheDir returns the cache directory set by opts and the default
+// options as a C string, or nil for the default cache dir. The returned
+// function releases it.
+func listingCacheDir(opts []Option) (*C.char, func()) {
+	o := newInitOptions(opts)
+	if o.cacheDir == "" {
+		return nil, func() {}
 	}
-	defer C.fastembed_model_info_vec_free(cVec)
-
-	models := (*[1 << 30]C.ModelInfoC)(unsafe.Pointer(cVec.models))[:cVec.len:cVec.len]
-	result := make([]ModelInfo, cVec.len)
-
-	for i, model := range models {
-		result[i] = ModelInfo{
-			ModelCode:   C.GoString(model.model_code),
-			Description: C.GoString(model.description),
-			Dimension:   int(model.dim),
-		}
-	}
-
-	return result
+	cDir := C.CString(o.cacheDir)
+	return cDir, func() { C.free(unsafe.Pointer(cDir)) }
 }
 
-// ListImageEmbeddingModels returns a list of all supported image embedding models
-func ListImageEmbeddingModels() []ModelInfo {
-	cVec := C.fastembed_image_embedding_list_supported_models()
-	if cVec == nil {
-		return nil
-	}
-	defer C.fastembed_model_info_vec_free(cVec)
-
-	models := (*[1 << 30]C.ModelInfoC)(unsafe.Pointer(cVec.models))[:cVec.len:cVec.len]
-	result := make([]ModelInfo, cVec.len)
-
-	for i, model := range models {
-		result[i] = ModelInfo{
-			ModelCode:   C.GoString(model.model_code),
-			Description: C.GoString(model.description),
-			Dimension:   int(model.dim),
-		}
-	}
-
-	return result
+// ListTextEmbeddingModels returns a list of all supported text embedding
+// models. Token limits and sizes of cached models are read from their files
+// in the cache dir given with WithCacheDir, or the default one; other
+// options are ignored.
+func ListTextEmbeddingModels(opts ...Option) []ModelInfo {
+	cDir, free := listingCacheDir(opts)
+	defer free()
+	return newModelInfos(C.fastembed_text_embedding_list_supported_models(cDir))
 }
 
-// ListTextRerankModels returns a list of all supported text reranking models
-func ListTextRerankModels() []ModelInfo {
-	cVec := C.fastembed_text_rerank_list_supported_models()
-	if cVec == nil {
-		return nil
-	}
-	defer C.fastembed_model_info_vec_free(cVec)
-
-	models := (*[1 << 30]C.ModelInfoC)(unsafe.Pointer(cVec.models))[:cVec.len:cVec.len]
-	result := make([]ModelInfo, cVec.len)
+// ListSparseTextEmbeddingModels returns a list of all supported sparse text
+// embedding models, reading cached models like ListTextEmbeddingModels
+func ListSparseTextEmbeddingModels(opts ...Option) []ModelInfo {
+	cDir, free := listingCacheDir(opts)
+	defer free()
+	return newModelInfos(C.fastembed_sparse_text_embedding_list_supported_models(cDir))
+}
 
-	for i, model := range models {
-		result[i] = ModelInfo{
-			ModelCode:   C.GoString(model.model_code),
-			Description: C.GoString(model.description),
-			Dimension:   int(model.dim),
-		}
-	}
+// ListImageEmbeddingModels returns a list of all supported image embedding
+// models, reading cached models like ListTextEmbeddingModels
+func ListImageEmbeddingModels(opts ...Option) []ModelInfo {
+	cDir, free := listingCacheDir(opts)
+	defer free()
+	return newModelInfos(C.fastembed_image_embedding_list_supported_models(cDir))
+}
 
-	return result
+// ListTextRerankModels returns a list of all supported text reranking
+// models, reading cached models like ListTextEmbeddingModels
+func ListTextRerankModels(opts ...Option) []ModelInfo {
+	cDir, free := listingCacheDir(opts)
+	defer free()
+	return newModelInfos(C.fastembed_text_rerank_list_supported_models(cDir))
 }
@@ -1,6 +1,10 @@
 package fastembed
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -158,3 +162,130 @@ func TestTextRerank_Rerank(t *testing.T) {
 		}
 	}
 }
+
+// TestTextEmbedding_WithOptions tests creating a model with a custom cache directory
+func TestTextEmbedding_WithOptions(t *testing.T) {
+
+	cacheDir := t.TempDir()
+	te, err := NewTextEmbedding("BGESmallENV15",
+		WithCacheDir(cacheDir),
+		WithMaxLength(128),
+		WithShowDownloadProgress(false),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer te.Close()
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to read cache directory: %v", err)
+	}
+	if len(entries) == 0 {
+		t.Error("Expected model files in the custom cache directory")
+	}
+
+	embeddings, err := te.Embed([]string{"Hello, World!"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed texts: %v", err)
+	}
+	if len(embeddings) != 1 {
+		t.Errorf("Expected 1 embedding, got %d", len(embeddings))
+	}
+}
+
+func TestModelDetails(t *testing.T) {
+	cacheDir := t.TempDir()
+	te, err := NewTextEmbedding("BGESmallENV15", WithCacheDir(cacheDir), WithMaxLength(256))
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer te.Close()
+
+	if te.ModelCode() != "Xenova/bge-small-en-v1.5" {
+		t.Errorf("Expected resolved model code, got %q", te.ModelCode())
+	}
+	if te.MaxLength() != 256 {
+		t.Errorf("Expected max length 256, got %d", te.MaxLength())
+	}
+	opts := te.Options()
+	if opts.CacheDir != cacheDir || opts.Offline || !opts.ShowDownloadProgress {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	embeddings, err := te.Embed([]string{"Hello, World!"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed texts: %v", err)
+	}
+	if te.Dimension() != len(embeddings[0]) {
+		t.Errorf("Expected dimension %d, got %d", len(embeddings[0]), te.Dimension())
+	}
+
+	ie, err := NewImageEmbedding("", WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("Failed to create image embedding: %v", err)
+	}
+	defer ie.Close()
+	if ie.Dimension() != 512 || ie.MaxLength() != 0 {
+		t.Errorf("Expected dimension 512 and no max length, got %d, %d", ie.Dimension(), ie.MaxLength())
+	}
+
+	tr, err := NewTextRerank("", WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("Failed to create text rerank: %v", err)
+	}
+	defer tr.Close()
+	if tr.ModelCode() != "BAAI/bge-reranker-base" || tr.Dimension() != 0 {
+		t.Errorf("Unexpected reranker details: %q, %d", tr.ModelCode(), tr.Dimension())
+	}
+}
+
+// TestTextEmbedding_Offline tests that offline mode fails fast on an empty cache
+// and succeeds once the model has been downloaded
+func TestTextEmbedding_Offline(t *testing.T) {
+
+	cacheDir := t.TempDir()
+	_, err := NewTextEmbedding("BGESmallENV15", WithCacheDir(cacheDir), WithOffline(true))
+	if err == nil {
+		t.Fatal("Expected an error for an empty cache in offline mode")
+	}
+	if !errors.Is(err, ErrModelNotCached) {
+		t.Errorf("Expected ErrModelNotCached, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "tokenizer.json") {
+		t.Errorf("Expected error to list the missing files, got: %v", err)
+	}
+
+	te, err := NewTextEmbedding("BGESmallENV15", WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("Failed to download text embedding: %v", err)
+	}
+	te.Close()
+
+	te, err = NewTextEmbedding("BGESmallENV15", WithCacheDir(cacheDir), WithOffline(true))
+	if err != nil {
+		t.Fatalf("Failed to load cached text embedding offline: %v", err)
+	}
+	te.Close()
+}
+
+func TestError_Is(t *testing.T) {
+	err := fmt.Errorf("embedding: %w", &Error{message: "batch failed", code: ErrorInference, model: "m", input: 3})
+	if !errors.Is(err, ErrInference) || errors.Is(err, ErrDownload) {
+		t.Errorf("Expected the error to match ErrInference only")
+	}
+
+	var fastembedErr *Error
+	if !errors.As(err, &fastembedErr) {
+		t.Fatal("Expected errors.As to find the *Error")
+	}
+	if fastembedErr.Code() != ErrorInference || fastembedErr.Model() != "m" || fastembedErr.Index() != 2 {
+		t.Errorf("Unexpected details: code %d, model %q, index %d", fastembedErr.Code(), fastembedErr.Model(), fastembedErr.Index())
+	}
+	if ErrClosed.Index() != -1 || ErrClosed.Code() != ErrorClosed {
+		t.Errorf("Expected ErrClosed to have its own code and no input")
+	}
+	if errors.Is(&Error{message: "uncoded"}, &Error{message: "also uncoded"}) {
+		t.Error("Errors without a code must not match each other")
+	}
+}
@@ -0,0 +1,180 @@
+package fastembed
+
+/*
+#include "fastembed.h"
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"context"
+	"io/fs"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// LateInteractionTextEmbedding represents a late-interaction (ColBERT) text
+// embedding model, which embeds every token of a text into its own
+// L2-normalized vector. Score the results with MaxSim or RankMaxSim. It is
+// safe for concurrent use; concurrent calls take turns running batches on
+// the model.
+//
+// fastembed-rs has no built-in late-interaction models, so the model is
+// loaded from an ONNX file whose output is the per-token last hidden state,
+// e.g. a ColBERT export such as answerdotai/answerai-colbert-small-v1. Its
+// tokenizer must have the BERT [unused0], [unused1] and [MASK] tokens, which
+// mark queries and documents the way ColBERT models are trained.
+type LateInteractionTextEmbedding struct {
+	mu     sync.RWMutex // held for reading by calls, for writing by Close
+	handle *C.LateInteractionTextEmbeddingHandle
+	modelDetails
+}
+
+// NewLateInteractionTextEmbeddingFromFiles creates a late-interaction text
+// embedding model from a local ONNX model file and its tokenizer files
+func NewLateInteractionTextEmbeddingFromFiles(onnxPath string, tokenizerFiles TokenizerFiles, opts ...Option) (*LateInteractionTextEmbedding, error) {
+	var cErr *C.FastEmbedError
+	cOnnxPath := C.CString(onnxPath)
+	defer C.free(unsafe.Pointer(cOnnxPath))
+
+	cPaths, freePaths := tokenizerFiles.toC()
+	defer freePaths()
+
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	handle := C.fastembed_late_interaction_text_embedding_new_from_files(cOnnxPath, cPaths, cOpts, &cErr)
+	if handle == nil {
+		return nil, newError(cErr)
+	}
+	return newLateInteractionTextEmbedding(handle, o), nil
+}
+
+// NewLateInteractionTextEmbeddingFromBytes creates a late-interaction text
+// embedding model from an ONNX model and tokenizer files held in memory
+func NewLateInteractionTextEmbeddingFromBytes(onnxModel []byte, tokenizer TokenizerBytes, opts ...Option) (*LateInteractionTextEmbedding, error) {
+	var cErr *C.FastEmbedError
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	cOnnx := cBytes(&pinner, onnxModel)
+	cTokenizer := tokenizer.toC(&pinner)
+
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	handle := C.fastembed_late_interaction_text_embedding_new_from_bytes(&cOnnx, cTokenizer, cOpts, &cErr)
+	if handle == nil {
+		return nil, newError(cErr)
+	}
+	return newLateInteractionTextEmbedding(handle, o), nil
+}
+
+// NewLateInteractionTextEmbeddingFromFS creates a late-interaction text
+// embedding model from files in fsys
+func NewLateInteractionTextEmbeddingFromFS(fsys fs.FS, onnxPath string, tokenizerFiles TokenizerFiles, opts ...Option) (*LateInteractionTextEmbedding, error) {
+	onnxModel, tokenizer, err := readModelFS(fsys, onnxPath, tokenizerFiles)
+	if err != nil {
+		return nil, err
+	}
+	return NewLateInteractionTextEmbeddingFromBytes(onnxModel, tokenizer, opts...)
+}
+
+func newLateInteractionTextEmbedding(handle *C.LateInteractionTextEmbeddingHandle, o *initOptions) *LateInteractionTextEmbedding {
+	var cDetails C.FastEmbedModelDetails
+	C.fastembed_late_interaction_text_embedding_details(handle, &cDetails)
+
+	le := &LateInteractionTextEmbedding{handle: handle, modelDetails: newModelDetails(&cDetails, o)}
+	runtime.SetFinalizer(le, func(l *LateInteractionTextEmbedding) {
+		l.Close()
+	})
+	return le
+}
+
+// EmbedQuery embeds search queries into one vector per token. Queries are
+// padded with [MASK] tokens to at least 32 tokens, as ColBERT expects.
+func (le *LateInteractionTextEmbedding) EmbedQuery(ctx context.Context, queries []string, opts ...CallOption) ([][][]float32, error) {
+	return le.embed(ctx, queries, true, opts)
+}
+
+// EmbedPassage embeds documents to be searched with EmbedQuery into one
+// vector per token, leaving out padding
+func (le *LateInteractionTextEmbedding) EmbedPassage(ctx context.Context, passages []string, opts ...CallOption) ([][][]float32, error) {
+	return le.embed(ctx, passages, false, opts)
+}
+
+func (le *LateInteractionTextEmbedding) embed(ctx context.Context, texts []string, isQuery bool, opts []CallOption) ([][][]float32, error) {
+	le.mu.RLock()
+	defer le.mu.RUnlock()
+	if le.handle == nil {
+		return nil, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o := newCallOptions(opts)
+	valid, kept, err := validateInputs(texts, o.skipInvalid, checkText)
+	if err != nil {
+		return nil, err
+	}
+	if len(valid) == 0 {
+		return spread([][][]float32{}, kept, len(texts)), nil
+	}
+
+	packed := packStrings(valid)
+
+	cancel, freeCancel := newCancelToken(ctx)
+	defer freeCancel()
+
+	var cErr *C.FastEmbedError
+	result := C.fastembed_late_interaction_text_embedding_embed(
+		le.handle,
+		packed.dataPtr(),
+		packed.offsetsPtr(),
+		packed.count(),
+		C.bool(isQuery),
+		C.size_t(o.batchSize),
+		cancel,
+		&cErr,
+	)
+	if result == nil {
+		return nil, newCallError(ctx, le.modelCode, cErr)
+	}
+	defer C.fastembed_float_array_vec_free(result)
+
+	// Split each text's rows by the model dimension
+	embeddings := make([][][]float32, int(result.len))
+	arrays := (*[1 << 30]C.FloatArray)(unsafe.Pointer(result.arrays))[:result.len:result.len]
+
+	for i, array := range arrays {
+		data := make([]float32, int(array.len))
+		if array.len > 0 {
+			cData := (*[1 << 30]C.float)(unsafe.Pointer(array.data))[:array.len:array.len]
+			for j, v := range cData {
+				data[j] = float32(v)
+			}
+		}
+		vectors := make([][]float32, 0, len(data)/le.dimension)
+		for start := 0; start+le.dimension <= len(data); start += le.dimension {
+			vectors = append(vectors, data[start:start+le.dimension:start+le.dimension])
+		}
+		embeddings[i] = vectors
+	}
+
+	return spread(embeddings, kept, len(texts)), nil
+}
+
+// Close releases the resources associated with the late-interaction model.
+// It waits for in-flight calls to finish and is safe to call more than once;
+// calls made after Close return ErrClosed.
+func (le *LateInteractionTextEmbedding) Close() {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	if le.handle != nil {
+		C.fastembed_late_interaction_text_embedding_free(le.handle)
+		le.handle = nil
+		runtime.SetFinalizer(le, nil)
+	}
+}
@@ -0,0 +1,129 @@
+package fastembed
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// colbertModel is a small ColBERT model with an ONNX export
+const colbertModel = "answerdotai/answerai-colbert-small-v1"
+
+// downloadColBERT downloads the ColBERT model files into a temporary
+// directory. fastembed-rs does not list late-interaction models, so they
+// cannot be fetched through the model cache.
+func downloadColBERT(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range []string{
+		"vespa_colbert.onnx",
+		defaultTokenizerFiles.Tokenizer,
+		defaultTokenizerFiles.Config,
+		defaultTokenizerFiles.SpecialTokensMap,
+		defaultTokenizerFiles.TokenizerConfig,
+	} {
+		resp, err := http.Get("https://huggingface.co/" + colbertModel + "/resolve/main/" + name)
+		if err != nil {
+			t.Skipf("Cannot download %s: %v", colbertModel, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			t.Fatalf("Failed to download %s: %s", name, resp.Status)
+		}
+		f, err := os.Create(filepath.Join(dir, name))
+		if err == nil {
+			_, err = io.Copy(f, resp.Body)
+			f.Close()
+		}
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Failed to download %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestLateInteractionTextEmbedding(t *testing.T) {
+	dir := downloadColBERT(t)
+	files := TokenizerFiles{
+		Tokenizer:        filepath.Join(dir, defaultTokenizerFiles.Tokenizer),
+		Config:           filepath.Join(dir, defaultTokenizerFiles.Config),
+		SpecialTokensMap: filepath.Join(dir, defaultTokenizerFiles.SpecialTokensMap),
+		TokenizerConfig:  filepath.Join(dir, defaultTokenizerFiles.TokenizerConfig),
+	}
+	model, err := NewLateInteractionTextEmbeddingFromFiles(filepath.Join(dir, "vespa_colbert.onnx"), files)
+	if err != nil {
+		t.Fatalf("Failed to create late-interaction text embedding: %v", err)
+	}
+	defer model.Close()
+
+	if model.Dimension() != 96 {
+		t.Errorf("Expected dimension 96, got %d", model.Dimension())
+	}
+
+	ctx := context.Background()
+	queries, err := model.EmbedQuery(ctx, []string{"What is a panda?"})
+	if err != nil {
+		t.Fatalf("Failed to embed query: %v", err)
+	}
+	if len(queries) != 1 || len(queries[0]) < 32 {
+		t.Fatalf("Expected one query padded to at least 32 token vectors, got %d", len(queries[0]))
+	}
+
+	documents := []string{
+		"The giant panda is a bear species endemic to China.",
+		"Paris is the capital of France.",
+		"A panda.",
+	}
+	passages, err := model.EmbedPassage(ctx, documents, WithBatchSize(2))
+	if err != nil {
+		t.Fatalf("Failed to embed passages: %v", err)
+	}
+	if len(passages) != len(documents) {
+		t.Fatalf("Expected %d passages, got %d", len(documents), len(passages))
+	}
+	if len(passages[2]) >= len(passages[0]) {
+		t.Errorf("Expected padding to be left out, got %d vectors for a short document and %d for a long one",
+			len(passages[2]), len(passages[0]))
+	}
+	for i, vectors := range append([][][]float32{queries[0]}, passages...) {
+		for _, v := range vectors {
+			if len(v) != model.Dimension() {
+				t.Fatalf("Expected vectors of dimension %d in embedding %d, got %d", model.Dimension(), i, len(v))
+			}
+			var norm float64
+			for _, x := range v {
+				norm += float64(x) * float64(x)
+			}
+			if math.Abs(math.Sqrt(norm)-1) > 1e-4 {
+				t.Fatalf("Expected normalized token vectors, got norm %f", math.Sqrt(norm))
+			}
+		}
+	}
+
+	results, err := RankMaxSim(queries[0], passages[:2])
+	if err != nil {
+		t.Fatalf("Failed to rank passages: %v", err)
+	}
+	if results[0].Index != 0 {
+		t.Errorf("Expected the panda passage to rank first, got %+v", results)
+	}
+
+	model.Close()
+	if _, err := model.EmbedQuery(ctx, []string{"query"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestNewLateInteractionTextEmbeddingFromFiles_MissingFile(t *testing.T) {
+	_, err := NewLateInteractionTextEmbeddingFromFiles(filepath.Join(t.TempDir(), "missing.onnx"), defaultTokenizerFiles)
+	if err == nil {
+		t.Fatal("Expected an error for a missing ONNX model")
+	}
+}
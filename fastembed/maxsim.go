@@ -0,0 +1,63 @@
+package fastembed
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MaxSim scores a document for a query the way late-interaction (ColBERT)
+// models do: each query token vector is matched with its most similar
+// document token vector and the dot products are summed. Vectors should
+// be normalized, as LateInteractionTextEmbedding outputs are. Vectors of
+// different dimensions return an ErrInvalidInput error. An empty
+// document scores 0.
+func MaxSim(query, document [][]float32) (float32, error) {
+	if len(document) == 0 {
+		return 0, nil
+	}
+	var score float32
+	for i, q := range query {
+		var best float32
+		for j, d := range document {
+			if len(d) != len(q) {
+				return 0, &Error{
+					message: fmt.Sprintf("document vector %d has dimension %d, query vector %d has %d", j, len(d), i, len(q)),
+					code:    ErrorInvalidInput,
+				}
+			}
+			if s := dot(q, d); j == 0 || s > best {
+				best = s
+			}
+		}
+		score += best
+	}
+	return score, nil
+}
+
+// RankMaxSim scores documents for a query with MaxSim and returns them
+// sorted by descending score, like TextRerank.Rerank. A document whose
+// vectors do not match the query dimension fails the call with an error
+// whose Index is the document's position.
+func RankMaxSim(query [][]float32, documents [][][]float32) ([]RerankResult, error) {
+	results := make([]RerankResult, len(documents))
+	for i, document := range documents {
+		score, err := MaxSim(query, document)
+		if err != nil {
+			return nil, &Error{message: fmt.Sprintf("document %d: %v", i, err), code: ErrorInvalidInput, input: i + 1}
+		}
+		results[i] = RerankResult{Index: i, Score: score}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results, nil
+}
+
+// dot returns the dot product of two vectors of the same length
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
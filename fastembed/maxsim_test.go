@@ -0,0 +1,51 @@
+package fastembed
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMaxSim(t *testing.T) {
+	query := [][]float32{{1, 0}, {0, 1}}
+	match := [][]float32{{0, 1}, {1, 0}, {0.6, 0.8}}
+	partial := [][]float32{{1, 0}, {1, 0}}
+
+	if got, err := MaxSim(query, match); err != nil || got != 2 {
+		t.Errorf("Expected each query token to find its match, got %f, %v", got, err)
+	}
+	if got, err := MaxSim(query, partial); err != nil || got != 1 {
+		t.Errorf("Expected only the first query token to match, got %f, %v", got, err)
+	}
+	if got, err := MaxSim(query, nil); err != nil || got != 0 {
+		t.Errorf("Expected 0 for an empty document, got %f, %v", got, err)
+	}
+	if got, err := MaxSim(query, [][]float32{{-1, 0}, {0, -1}}); err != nil || got != 0 {
+		t.Errorf("Expected the best of negative similarities to be 0, got %f, %v", got, err)
+	}
+
+	results, err := RankMaxSim(query, [][][]float32{partial, match, nil})
+	if err != nil {
+		t.Fatalf("Failed to rank documents: %v", err)
+	}
+	if results[0].Index != 1 || results[1].Index != 0 || results[2].Index != 2 {
+		t.Errorf("Unexpected ranking: %+v", results)
+	}
+}
+
+func TestMaxSim_DimensionMismatch(t *testing.T) {
+	query := [][]float32{{1, 0, 0}}
+	short := [][]float32{{1, 0}}
+
+	if _, err := MaxSim(query, short); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for a shorter document vector, got %v", err)
+	}
+	if _, err := MaxSim(short, query); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for a longer document vector, got %v", err)
+	}
+
+	_, err := RankMaxSim(query, [][][]float32{{{0, 1, 0}}, short})
+	var fe *Error
+	if !errors.As(err, &fe) || fe.Code() != ErrorInvalidInput || fe.Index() != 1 {
+		t.Errorf("Expected an invalid input error for document 1, got %v", err)
+	}
+}
@@ -1,6 +1,8 @@
 package fastembed
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -24,6 +26,14 @@ func TestListTextEmbeddingModels(t *testing.T) {
 	}
 }
 
+func TestListTextEmbeddingModels_Metadata(t *testing.T) {
+	for _, model := range ListTextEmbeddingModels() {
+		if model.MaxTokens <= 0 || model.Dimension <= 0 {
+			t.Errorf("Expected max tokens and dimension for %s, got %d and %d", model.ModelCode, model.MaxTokens, model.Dimension)
+		}
+	}
+}
+
 func TestListSparseTextEmbeddingModels(t *testing.T) {
 	models := ListSparseTextEmbeddingModels()
 	if len(models) == 0 {
@@ -78,6 +88,51 @@ func TestListTextRerankModels(t *testing.T) {
 	}
 }
 
+func TestModelInfo_Metadata(t *testing.T) {
+	find := func(models []ModelInfo, code string) ModelInfo {
+		t.Helper()
+		for _, m := range models {
+			if m.ModelCode == code && !m.Quantized {
+				return m
+			}
+		}
+		t.Fatalf("Model %s not found", code)
+		return ModelInfo{}
+	}
+
+	bge := find(ListTextEmbeddingModels(), "Xenova/bge-small-en-v1.5")
+	if bge.Pooling != PoolingCLS {
+		t.Errorf("Expected CLS pooling for %s, got %d", bge.ModelCode, bge.Pooling)
+	}
+	if bge.MaxTokens != 512 {
+		t.Errorf("Expected 512 max tokens for %s, got %d", bge.ModelCode, bge.MaxTokens)
+	}
+	if !strings.HasPrefix(bge.QueryPrefix, "Represent this sentence") || bge.PassagePrefix != "" {
+		t.Errorf("Unexpected prefixes for %s: %q, %q", bge.ModelCode, bge.QueryPrefix, bge.PassagePrefix)
+	}
+	if !strings.HasSuffix(bge.ModelFile, ".onnx") || bge.ApproxSize == 0 {
+		t.Errorf("Expected model file and size for %s, got %q, %d", bge.ModelCode, bge.ModelFile, bge.ApproxSize)
+	}
+
+	quantized := 0
+	for _, m := range ListTextEmbeddingModels() {
+		if m.Quantized {
+			quantized++
+		}
+	}
+	if quantized == 0 {
+		t.Error("Expected quantized text embedding models")
+	}
+
+	rerankers := ListTextRerankModels()
+	if find(rerankers, "BAAI/bge-reranker-base").Multilingual {
+		t.Error("Expected BAAI/bge-reranker-base not to be multilingual")
+	}
+	if !find(rerankers, "rozgo/bge-reranker-v2-m3").Multilingual {
+		t.Error("Expected bge-reranker-v2-m3 to be multilingual")
+	}
+}
+
 // Test that we can use different models by their model codes
 func TestNewSparseTextEmbeddingWithModelCode(t *testing.T) {
 	models := ListSparseTextEmbeddingModels()
@@ -163,3 +218,52 @@ func TestNewTextRerankWithModelCode(t *testing.T) {
 		t.Logf("  %d. Score: %.4f, Index: %d, Document: %s", i+1, result.Score, result.Index, result.Document)
 	}
 }
+
+func TestNewTextEmbeddingWithModelCode(t *testing.T) {
+	models := ListTextEmbeddingModels()
+	if len(models) == 0 {
+		t.Skip("No text embedding models available")
+	}
+
+	// Try using the model code from the list
+	modelCode := models[0].ModelCode
+	t.Logf("Testing with model code: %s", modelCode)
+
+	model, err := NewTextEmbedding(modelCode)
+	if err != nil {
+		t.Fatalf("Failed to create text embedding with model code %s: %v", modelCode, err)
+	}
+	defer model.Close()
+
+	embeddings, err := model.Embed([]string{"Hello, world!"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed with model %s: %v", modelCode, err)
+	}
+
+	if len(embeddings) != 1 {
+		t.Fatalf("Expected 1 embedding, got %d", len(embeddings))
+	}
+	if len(embeddings[0]) != models[0].Dimension {
+		t.Errorf("Expected dimension %d for %s, got %d", models[0].Dimension, modelCode, len(embeddings[0]))
+	}
+}
+
+func TestNewTextEmbeddingUnknownModel(t *testing.T) {
+	model, err := NewTextEmbedding("bge-smal-en-v1.5")
+	if err == nil {
+		model.Close()
+		t.Fatal("Expected an error for an unknown model name")
+	}
+
+	t.Logf("Error: %v", err)
+	if !strings.Contains(err.Error(), "bge-small-en-v1.5") {
+		t.Errorf("Expected error to suggest bge-small-en-v1.5, got: %v", err)
+	}
+	if !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("Expected ErrUnknownModel, got: %v", err)
+	}
+	var fastembedErr *Error
+	if !errors.As(err, &fastembedErr) || fastembedErr.Model() != "bge-smal-en-v1.5" {
+		t.Errorf("Expected the error to name the requested model, got: %v", err)
+	}
+}
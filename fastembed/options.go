@@ -0,0 +1,189 @@
+package fastembed
+
+/*
+#include "fastembed.h"
+#include <stdlib.h>
+
+extern void goDownloadProgress(uintptr_t, char*, char*, uint64_t, uint64_t);
+*/
+import "C"
+import (
+	"runtime/cgo"
+	"sync"
+	"unsafe"
+)
+
+// Option configures how a model is initialized
+type Option func(*initOptions)
+
+// initOptions holds the settings collected from Option values. Zero values
+// keep the fastembed-rs defaults.
+type initOptions struct {
+	cacheDir             string
+	maxLength            int
+	showDownloadProgress *bool
+	offline              bool
+	endpoint             string
+	authToken            string
+	progress             func(DownloadProgress)
+	reporter             *progressReporter
+	queryPrefix          *string
+	passagePrefix        *string
+}
+
+var (
+	defaultOptionsMu sync.RWMutex
+	defaultOptions   []Option
+)
+
+// SetDefaultOptions sets process-wide options that apply to every model
+// created afterwards. Options passed to a constructor are applied on top of
+// them. Calling SetDefaultOptions again replaces the previous defaults.
+func SetDefaultOptions(opts ...Option) {
+	defaultOptionsMu.Lock()
+	defer defaultOptionsMu.Unlock()
+	defaultOptions = append([]Option(nil), opts...)
+}
+
+// WithCacheDir sets the directory where model files are downloaded and cached
+func WithCacheDir(dir string) Option {
+	return func(o *initOptions) {
+		o.cacheDir = dir
+	}
+}
+
+// WithMaxLength sets the maximum input sequence length in tokens.
+// It has no effect on image embedding models.
+func WithMaxLength(maxLength int) Option {
+	return func(o *initOptions) {
+		o.maxLength = maxLength
+	}
+}
+
+// WithShowDownloadProgress controls whether model download progress is printed
+func WithShowDownloadProgress(show bool) Option {
+	return func(o *initOptions) {
+		o.showDownloadProgress = &show
+	}
+}
+
+// WithOffline makes model creation use only files that are already in the
+// model cache. No download is attempted; if files are missing, the
+// constructor fails immediately with an error matching ErrModelNotCached
+// that lists them.
+func WithOffline(offline bool) Option {
+	return func(o *initOptions) {
+		o.offline = offline
+	}
+}
+
+// WithEndpoint sets the base URL that model files are downloaded from, e.g.
+// an internal Hugging Face mirror. Files are requested as
+// {endpoint}/{model code}/resolve/main/{file}.
+func WithEndpoint(baseURL string) Option {
+	return func(o *initOptions) {
+		o.endpoint = baseURL
+	}
+}
+
+// WithAuthToken sets a bearer token that is sent with model download requests
+func WithAuthToken(token string) Option {
+	return func(o *initOptions) {
+		o.authToken = token
+	}
+}
+
+// WithProgressFunc sets a function that is called with the progress of each
+// model file download. Files that are already cached are reported once as
+// complete. The function is called synchronously from the constructor or
+// Prefetch call that downloads the model. If it panics, it is not called
+// again and that call returns an error matching ErrPanic once the download
+// ends.
+func WithProgressFunc(fn func(DownloadProgress)) Option {
+	return func(o *initOptions) {
+		o.progress = fn
+	}
+}
+
+// WithQueryPrefix sets the prefix that EmbedQuery adds to queries, replacing
+// the one recommended for the model. An empty prefix disables it.
+func WithQueryPrefix(prefix string) Option {
+	return func(o *initOptions) {
+		o.queryPrefix = &prefix
+	}
+}
+
+// WithPassagePrefix sets the prefix that EmbedPassage adds to passages,
+// replacing the one recommended for the model. An empty prefix disables it.
+func WithPassagePrefix(prefix string) Option {
+	return func(o *initOptions) {
+		o.passagePrefix = &prefix
+	}
+}
+
+// newInitOptions applies the given options on top of the defaults
+func newInitOptions(opts []Option) *initOptions {
+	defaultOptionsMu.RLock()
+	defaults := defaultOptions
+	defaultOptionsMu.RUnlock()
+
+	o := &initOptions{}
+	for _, opt := range append(defaults[:len(defaults):len(defaults)], opts...) {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// toC converts the options to their C representation. The returned function
+// releases the C memory and must be called once the options are no longer used.
+func (o *initOptions) toC() (*C.FastEmbedInitOptions, func()) {
+	cOpts := &C.FastEmbedInitOptions{
+		max_length:             cSize(o.maxLength),
+		show_download_progress: -1,
+		offline:                C.bool(o.offline),
+	}
+	if o.showDownloadProgress != nil {
+		if *o.showDownloadProgress {
+			cOpts.show_download_progress = 1
+		} else {
+			cOpts.show_download_progress = 0
+		}
+	}
+	if o.cacheDir != "" {
+		cOpts.cache_dir = C.CString(o.cacheDir)
+	}
+	if o.endpoint != "" {
+		cOpts.endpoint = C.CString(o.endpoint)
+	}
+	if o.authToken != "" {
+		cOpts.token = C.CString(o.authToken)
+	}
+	var progress cgo.Handle
+	if o.progress != nil {
+		o.reporter = &progressReporter{fn: o.progress}
+		progress = cgo.NewHandle(o.reporter)
+		cOpts.progress = C.FastEmbedProgressCallback(C.goDownloadProgress)
+		cOpts.progress_user_data = C.uintptr_t(progress)
+	}
+
+	return cOpts, func() {
+		for _, p := range []*C.char{cOpts.cache_dir, cOpts.endpoint, cOpts.token} {
+			if p != nil {
+				C.free(unsafe.Pointer(p))
+			}
+		}
+		if progress != 0 {
+			progress.Delete()
+		}
+	}
+}
+
+// cSize converts n to a C size_t, mapping negative values to zero
+func cSize(n int) C.size_t {
+	if n < 0 {
+		return 0
+	}
+	return C.size_t(n)
+}
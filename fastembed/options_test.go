@@ -0,0 +1,105 @@
+package fastembed
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInitOptions_ToC(t *testing.T) {
+	cOpts, free := newInitOptions(nil).toC()
+	if cOpts.cache_dir != nil {
+		t.Error("Expected nil cache_dir by default")
+	}
+	if cOpts.max_length != 0 {
+		t.Error("Expected zero numeric options by default")
+	}
+	if cOpts.show_download_progress != -1 {
+		t.Errorf("Expected show_download_progress -1 by default, got %d", cOpts.show_download_progress)
+	}
+	free()
+
+	cOpts, free = newInitOptions([]Option{
+		WithCacheDir("/tmp/models"),
+		WithMaxLength(256),
+		WithShowDownloadProgress(false),
+	}).toC()
+	defer free()
+
+	if cOpts.cache_dir == nil {
+		t.Fatal("Expected cache_dir to be set")
+	}
+	if cOpts.max_length != 256 {
+		t.Errorf("Expected max_length 256, got %d", cOpts.max_length)
+	}
+	if cOpts.show_download_progress != 0 {
+		t.Errorf("Expected show_download_progress 0, got %d", cOpts.show_download_progress)
+	}
+}
+
+func TestSetDefaultOptions(t *testing.T) {
+	SetDefaultOptions(WithOffline(true), WithCacheDir("/var/cache/fastembed"))
+	defer SetDefaultOptions()
+
+	o := newInitOptions([]Option{WithCacheDir("/tmp/models")})
+	if !o.offline {
+		t.Error("Expected offline from the default options")
+	}
+	if o.cacheDir != "/tmp/models" {
+		t.Errorf("Expected constructor options to override defaults, got cache dir %q", o.cacheDir)
+	}
+
+	SetDefaultOptions()
+	if newInitOptions(nil).offline {
+		t.Error("Expected defaults to be cleared")
+	}
+}
+
+func TestInitOptions_ProgressFunc(t *testing.T) {
+	cOpts, free := newInitOptions(nil).toC()
+	if cOpts.progress != nil || cOpts.progress_user_data != 0 {
+		t.Error("Expected no progress callback by default")
+	}
+	free()
+
+	var reported []DownloadProgress
+	cOpts, free = newInitOptions([]Option{WithProgressFunc(func(p DownloadProgress) {
+		reported = append(reported, p)
+	})}).toC()
+	defer free()
+
+	if cOpts.progress == nil || cOpts.progress_user_data == 0 {
+		t.Fatal("Expected progress callback to be set")
+	}
+	goDownloadProgress(cOpts.progress_user_data, nil, nil, 5, 10)
+	if len(reported) != 1 || reported[0].Downloaded != 5 || reported[0].Total != 10 {
+		t.Errorf("Unexpected progress: %v", reported)
+	}
+}
+
+func TestInitOptions_ProgressFuncPanic(t *testing.T) {
+	calls := 0
+	o := newInitOptions([]Option{WithProgressFunc(func(DownloadProgress) {
+		calls++
+		panic("boom")
+	})})
+	cOpts, free := o.toC()
+	defer free()
+
+	if err := o.progressErr("org/model", nil); err != nil {
+		t.Fatalf("Expected no error before a panic, got %v", err)
+	}
+	goDownloadProgress(cOpts.progress_user_data, nil, nil, 5, 10)
+	goDownloadProgress(cOpts.progress_user_data, nil, nil, 10, 10)
+	if calls != 1 {
+		t.Errorf("Expected the progress function to be called once after panicking, got %d calls", calls)
+	}
+
+	err := o.progressErr("org/model", nil)
+	if !errors.Is(err, ErrPanic) {
+		t.Fatalf("Expected ErrPanic, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected the panic value in the error, got %q", err)
+	}
+}
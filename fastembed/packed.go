@@ -0,0 +1,58 @@
+package fastembed
+
+/*
+#include "fastembed.h"
+*/
+import "C"
+import "unsafe"
+
+// packedStrings holds strings concatenated into one buffer, the way the C
+// API takes its inputs: string i is data[offsets[i]:offsets[i+1]]. Packing
+// costs two allocations per call instead of one C string per input, and
+// keeps NUL bytes intact.
+type packedStrings struct {
+	data    []byte
+	offsets []C.size_t
+}
+
+func packStrings(strs []string) packedStrings {
+	total := 0
+	for _, s := range strs {
+		total += len(s)
+	}
+
+	// One spare byte keeps the data pointer valid when all strings are empty
+	p := packedStrings{
+		data:    make([]byte, 0, total+1),
+		offsets: make([]C.size_t, 1, len(strs)+1),
+	}
+	for _, s := range strs {
+		p.data = append(p.data, s...)
+		p.offsets = append(p.offsets, C.size_t(len(p.data)))
+	}
+	return p
+}
+
+// dataPtr returns the buffer for the C call. Like offsetsPtr, it points to
+// Go memory without Go pointers, which may be passed to C for the duration
+// of a call.
+func (p packedStrings) dataPtr() *C.char {
+	return (*C.char)(unsafe.Pointer(unsafe.SliceData(p.data)))
+}
+
+func (p packedStrings) offsetsPtr() *C.size_t {
+	return &p.offsets[0]
+}
+
+func (p packedStrings) count() C.size_t {
+	return C.size_t(len(p.offsets) - 1)
+}
+
+// boolsPtr returns the buffer of a flag slice for a C call, or NULL if the
+// slice is empty. Go and C bools are both one byte.
+func boolsPtr(b []bool) *C.bool {
+	if len(b) == 0 {
+		return nil
+	}
+	return (*C.bool)(unsafe.Pointer(&b[0]))
+}
@@ -0,0 +1,53 @@
+package fastembed
+
+import (
+	"testing"
+)
+
+func TestPackStrings(t *testing.T) {
+	inputs := []string{"hello", "", "nul\x00byte", "ünïcödé"}
+	p := packStrings(inputs)
+
+	if int(p.count()) != len(inputs) {
+		t.Fatalf("Expected %d strings, got %d", len(inputs), p.count())
+	}
+	for i, want := range inputs {
+		got := string(p.data[p.offsets[i]:p.offsets[i+1]])
+		if got != want {
+			t.Errorf("String %d: expected %q, got %q", i, want, got)
+		}
+	}
+
+	empty := packStrings(nil)
+	if empty.count() != 0 || empty.dataPtr() == nil || empty.offsetsPtr() == nil {
+		t.Error("Expected valid pointers for empty input")
+	}
+	if blank := packStrings([]string{"", ""}); blank.dataPtr() == nil {
+		t.Error("Expected a valid data pointer when all strings are empty")
+	}
+}
+
+func TestTextEmbedding_NULBytes(t *testing.T) {
+	model, err := NewTextEmbedding("")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer model.Close()
+
+	embeddings, err := model.Embed([]string{"first part", "first part\x00second part"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed: %v", err)
+	}
+
+	// A truncated text would embed exactly like its prefix
+	same := true
+	for i := range embeddings[0] {
+		if embeddings[0][i] != embeddings[1][i] {
+			same = false
+			break
+		}
+	}
+	if same {
+		t.Error("Expected the text after the NUL byte to be embedded as well")
+	}
+}
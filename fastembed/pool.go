@@ -0,0 +1,284 @@
+package fastembed
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Model is the set of model types a Pool can hold
+type Model interface {
+	*TextEmbedding | *SparseTextEmbedding | *ImageEmbedding | *TextRerank
+	Close()
+}
+
+// ErrPoolTimeout is returned by Pool.Acquire when no model became available
+// within the pool's maximum wait time
+var ErrPoolTimeout = &Error{message: "timed out waiting for a pooled model", code: ErrorPoolTimeout}
+
+// PoolOption configures a Pool
+type PoolOption func(*poolOptions)
+
+type poolOptions struct {
+	maxWait time.Duration
+}
+
+// WithMaxWait bounds how long Acquire waits for a free model before failing
+// with ErrPoolTimeout. Zero, the default, waits until the context is done.
+func WithMaxWait(d time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.maxWait = d
+	}
+}
+
+// PoolStats is a snapshot of a pool's state and counters
+type PoolStats struct {
+	Size     int           // Target number of models
+	Idle     int           // Models waiting to be acquired
+	InUse    int           // Models currently acquired
+	Waiting  int           // Callers waiting for a model
+	Acquired uint64        // Successful Acquire calls
+	Timeouts uint64        // Acquire calls that gave up because of MaxWait or their context
+	WaitTime time.Duration // Total time callers spent waiting for a model
+}
+
+// Utilization returns the fraction of the pool's models that are in use
+func (s PoolStats) Utilization() float64 {
+	if s.Size == 0 {
+		return 0
+	}
+	return float64(s.InUse) / float64(s.Size)
+}
+
+// Pool holds several instances of the same model and hands them out to
+// concurrent callers, so that inference runs in parallel without loading
+// the model for every goroutine. It is safe for concurrent use.
+type Pool[M Model] struct {
+	newModel func() (M, error)
+	maxWait  time.Duration
+
+	mu       sync.Mutex
+	size     int
+	models   int // models that exist or are being created
+	creating int
+	idle     []M
+	waiters  []chan M // FIFO; closed when the pool is closed
+	closed   bool
+	acquired uint64
+	timeouts uint64
+	waitTime time.Duration
+}
+
+// NewPool creates a pool of size models, all created by newModel up front:
+//
+//	pool, err := fastembed.NewPool(4, func() (*fastembed.TextEmbedding, error) {
+//		return fastembed.NewTextEmbedding("BGESmallENV15")
+//	})
+func NewPool[M Model](size int, newModel func() (M, error), opts ...PoolOption) (*Pool[M], error) {
+	o := &poolOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+
+	p := &Pool[M]{newModel: newModel, maxWait: o.maxWait}
+	if err := p.Resize(size); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Acquire returns a model from the pool, waiting until one is released if
+// all are in use. It fails with ctx.Err() when ctx is done, ErrPoolTimeout
+// when the pool's maximum wait passes and ErrClosed once the pool is closed.
+// The model must be given back with Release.
+func (p *Pool[M]) Acquire(ctx context.Context) (M, error) {
+	var zero M
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return zero, ErrClosed
+	}
+	if n := len(p.idle); n > 0 {
+		m := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.acquired++
+		p.mu.Unlock()
+		return m, nil
+	}
+	w := make(chan M, 1)
+	p.waiters = append(p.waiters, w)
+	p.mu.Unlock()
+
+	start := time.Now()
+	var timeout <-chan time.Time
+	if p.maxWait > 0 {
+		timer := time.NewTimer(p.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case m, ok := <-w:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.waitTime += time.Since(start)
+		if !ok {
+			return zero, ErrClosed
+		}
+		p.acquired++
+		return m, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrPoolTimeout
+	}
+
+	p.mu.Lock()
+	p.waitTime += time.Since(start)
+	p.timeouts++
+	removed := p.removeWaiter(w)
+	p.mu.Unlock()
+	if !removed {
+		// A model was handed over, or the pool closed, while giving up
+		if m, ok := <-w; ok {
+			p.Release(m)
+		}
+	}
+	return zero, err
+}
+
+func (p *Pool[M]) removeWaiter(w chan M) bool {
+	for i, waiter := range p.waiters {
+		if waiter == w {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Release gives a model obtained from Acquire back to the pool. Models
+// beyond the pool's size after a shrinking Resize, and models released
+// after Close, are closed instead.
+func (p *Pool[M]) Release(m M) {
+	p.mu.Lock()
+	if p.closed || p.models > p.size {
+		p.models--
+		p.mu.Unlock()
+		m.Close()
+		return
+	}
+	if len(p.waiters) > 0 {
+		w := p.waiters[0]
+		p.waiters = p.waiters[1:]
+		w <- m
+		p.mu.Unlock()
+		return
+	}
+	p.idle = append(p.idle, m)
+	p.mu.Unlock()
+}
+
+// Do acquires a model, calls fn with it and releases it again
+func (p *Pool[M]) Do(ctx context.Context, fn func(M) error) error {
+	m, err := p.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Release(m)
+	return fn(m)
+}
+
+// Resize changes the number of models in the pool. New models are created
+// before Resize returns; when shrinking, idle models are closed right away
+// and models in use are closed when they are released.
+func (p *Pool[M]) Resize(size int) error {
+	if size < 1 {
+		return &Error{message: "pool size must be at least 1", code: ErrorInvalidInput}
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.size = size
+	var excess []M
+	for p.models > p.size && len(p.idle) > 0 {
+		n := len(p.idle)
+		excess = append(excess, p.idle[n-1])
+		p.idle = p.idle[:n-1]
+		p.models--
+	}
+	missing := p.size - p.models
+	if missing > 0 {
+		p.models += missing
+		p.creating += missing
+	}
+	p.mu.Unlock()
+
+	for _, m := range excess {
+		m.Close()
+	}
+	for i := 0; i < missing; i++ {
+		m, err := p.newModel()
+		p.mu.Lock()
+		p.creating--
+		if err != nil {
+			// Give up the reservations of this and the remaining models
+			p.models -= missing - i
+			p.creating -= missing - i - 1
+			p.mu.Unlock()
+			return err
+		}
+		p.mu.Unlock()
+		p.Release(m)
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the pool's state and counters
+func (p *Pool[M]) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PoolStats{
+		Size:     p.size,
+		Idle:     len(p.idle),
+		InUse:    p.models - p.creating - len(p.idle),
+		Waiting:  len(p.waiters),
+		Acquired: p.acquired,
+		Timeouts: p.timeouts,
+		WaitTime: p.waitTime,
+	}
+}
+
+// Close closes the idle models and fails waiting and future Acquire calls
+// with ErrClosed. Models still in use are closed when they are released.
+func (p *Pool[M]) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.models -= len(idle)
+	for _, w := range p.waiters {
+		close(w)
+	}
+	p.waiters = nil
+	p.mu.Unlock()
+
+	for _, m := range idle {
+		m.Close()
+	}
+}
@@ -0,0 +1,204 @@
+package fastembed
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestPool returns a pool of closed TextEmbedding values, which stand in
+// for loaded models without needing the ONNX runtime
+func newTestPool(t *testing.T, size int, opts ...PoolOption) (*Pool[*TextEmbedding], *int) {
+	t.Helper()
+	var created int
+	pool, err := NewPool(size, func() (*TextEmbedding, error) {
+		created++
+		return &TextEmbedding{}, nil
+	}, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool, &created
+}
+
+func TestPool_AcquireRelease(t *testing.T) {
+	pool, created := newTestPool(t, 2)
+	if *created != 2 {
+		t.Fatalf("Expected 2 models to be created, got %d", *created)
+	}
+
+	a, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire model: %v", err)
+	}
+	b, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire model: %v", err)
+	}
+	if a == b {
+		t.Error("Expected distinct models")
+	}
+
+	stats := pool.Stats()
+	if stats.Size != 2 || stats.InUse != 2 || stats.Idle != 0 || stats.Utilization() != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	pool.Release(a)
+	pool.Release(b)
+	stats = pool.Stats()
+	if stats.InUse != 0 || stats.Idle != 2 || stats.Acquired != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestPool_Wait(t *testing.T) {
+	pool, _ := newTestPool(t, 1, WithMaxWait(20*time.Millisecond))
+
+	m, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire model: %v", err)
+	}
+
+	var fe *Error
+	if _, err := pool.Acquire(context.Background()); !errors.As(err, &fe) || fe.Code() != ErrorPoolTimeout {
+		t.Errorf("Expected ErrPoolTimeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	got := make(chan *TextEmbedding)
+	go func() {
+		m, err := pool.Acquire(context.Background())
+		if err != nil {
+			t.Errorf("Failed to acquire released model: %v", err)
+		}
+		got <- m
+	}()
+	for pool.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	pool.Release(m)
+	if <-got != m {
+		t.Error("Expected the waiting caller to receive the released model")
+	}
+
+	stats := pool.Stats()
+	if stats.Timeouts != 1 || stats.WaitTime == 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestPool_Resize(t *testing.T) {
+	pool, created := newTestPool(t, 2)
+
+	if err := pool.Resize(4); err != nil {
+		t.Fatalf("Failed to grow pool: %v", err)
+	}
+	if *created != 4 || pool.Stats().Idle != 4 {
+		t.Fatalf("Expected 4 idle models, got %+v", pool.Stats())
+	}
+
+	m, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire model: %v", err)
+	}
+	if err := pool.Resize(1); err != nil {
+		t.Fatalf("Failed to shrink pool: %v", err)
+	}
+	if stats := pool.Stats(); stats.Idle != 0 || stats.InUse != 1 {
+		t.Errorf("Expected idle models to be closed, got %+v", stats)
+	}
+
+	pool.Release(m)
+	if stats := pool.Stats(); stats.Size != 1 || stats.Idle != 1 {
+		t.Errorf("Expected one idle model after release, got %+v", stats)
+	}
+
+	if err := pool.Resize(0); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput for an empty pool, got %v", err)
+	}
+}
+
+func TestPool_NewModelError(t *testing.T) {
+	calls := 0
+	_, err := NewPool(3, func() (*TextEmbedding, error) {
+		calls++
+		if calls == 2 {
+			return nil, errors.New("load failed")
+		}
+		return &TextEmbedding{}, nil
+	})
+	if err == nil || err.Error() != "load failed" {
+		t.Errorf("Expected the model creation error, got %v", err)
+	}
+}
+
+func TestPool_Close(t *testing.T) {
+	pool, _ := newTestPool(t, 1)
+
+	m, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to acquire model: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := pool.Acquire(context.Background()); !errors.Is(err, ErrClosed) {
+			t.Errorf("Expected ErrClosed for a waiting caller, got %v", err)
+		}
+	}()
+	for pool.Stats().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	pool.Close()
+	wg.Wait()
+	pool.Release(m)
+
+	if _, err := pool.Acquire(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	if stats := pool.Stats(); stats.Idle != 0 || stats.InUse != 0 {
+		t.Errorf("Expected no models after close, got %+v", stats)
+	}
+}
+
+func TestPool_TextEmbedding(t *testing.T) {
+	pool, err := NewPool(2, func() (*TextEmbedding, error) {
+		return NewTextEmbedding("")
+	})
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.Do(context.Background(), func(te *TextEmbedding) error {
+				_, err := te.Embed([]string{"Hello, World!"}, 0)
+				return err
+			})
+			if err != nil {
+				t.Errorf("Failed to embed with pooled model: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stats := pool.Stats(); stats.Acquired != 8 || stats.InUse != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
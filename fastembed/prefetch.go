@@ -0,0 +1,92 @@
+package fastembed
+
+/*
+#include "fastembed.h"
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"fmt"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// DownloadProgress reports how much of one model file has been downloaded
+type DownloadProgress struct {
+	ModelCode  string // Hugging Face model code
+	File       string // File within the model repository, e.g. "model.onnx"
+	Downloaded int64  // Bytes downloaded so far
+	Total      int64  // Size of the file in bytes, 0 if unknown
+}
+
+// progressReporter passes download progress to a WithProgressFunc function
+// and records a panic in it, which must not unwind through the Rust frames
+// that call goDownloadProgress
+type progressReporter struct {
+	fn        func(DownloadProgress)
+	recovered any // Value of the first panic in fn, nil if none
+}
+
+//export goDownloadProgress
+func goDownloadProgress(userData C.uintptr_t, modelCode, file *C.char, done, total C.uint64_t) {
+	r := cgo.Handle(userData).Value().(*progressReporter)
+	if r.recovered != nil {
+		return
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			r.recovered = v
+		}
+	}()
+	r.fn(DownloadProgress{
+		ModelCode:  C.GoString(modelCode),
+		File:       C.GoString(file),
+		Downloaded: int64(done),
+		Total:      int64(total),
+	})
+}
+
+// progressErr returns an ErrPanic error if the progress function panicked
+// during the call that used the options, releasing that call's cErr, or nil
+func (o *initOptions) progressErr(model string, cErr *C.FastEmbedError) error {
+	if o.reporter == nil || o.reporter.recovered == nil {
+		return nil
+	}
+	C.fastembed_error_free(cErr)
+	return &Error{
+		message: fmt.Sprintf("download progress function panicked: %v", o.reporter.recovered),
+		code:    ErrorPanic,
+		model:   model,
+	}
+}
+
+// Prefetch downloads all files of a model into the cache without loading it,
+// e.g. in a container build step. The model name is resolved like in the
+// constructor of the given kind; an empty name selects its default model.
+// Use WithProgressFunc to observe the download. With WithOffline, Prefetch
+// only checks that the model is fully cached and returns an error matching
+// ErrModelNotCached otherwise.
+func Prefetch(kind ModelKind, modelName string, opts ...Option) error {
+	var cErr *C.FastEmbedError
+	var cModelName *C.char
+	if modelName != "" {
+		cModelName = C.CString(modelName)
+		defer C.free(unsafe.Pointer(cModelName))
+	}
+
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	ok := C.fastembed_prefetch(C.int(kind), cModelName, cOpts, &cErr)
+	if err := o.progressErr(modelName, cErr); err != nil {
+		return err
+	}
+	if !ok {
+		if err := newModelError(modelName, cErr); err != nil {
+			return err
+		}
+		return &Error{message: "prefetch failed"}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package fastembed
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestPrefetch(t *testing.T) {
+	upstream := t.TempDir()
+	if err := Prefetch(KindTextEmbedding, "Xenova/bge-small-en-v1.5", WithCacheDir(upstream)); err != nil {
+		t.Fatalf("Failed to prefetch model: %v", err)
+	}
+	srv, _ := newMirror(t, "Xenova/bge-small-en-v1.5", snapshotDir(t, upstream, "Xenova/bge-small-en-v1.5"))
+
+	var mu sync.Mutex
+	progress := make(map[string]DownloadProgress)
+	record := WithProgressFunc(func(p DownloadProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if p.Downloaded > p.Total && p.Total > 0 {
+			t.Errorf("%s: downloaded %d of %d bytes", p.File, p.Downloaded, p.Total)
+		}
+		progress[p.File] = p
+	})
+
+	cacheDir := t.TempDir()
+	if err := Prefetch(KindTextEmbedding, "BGESmallENV15", WithCacheDir(cacheDir), WithEndpoint(srv.URL), record); err != nil {
+		t.Fatalf("Failed to prefetch model from mirror: %v", err)
+	}
+
+	mu.Lock()
+	if len(progress) == 0 {
+		t.Error("Expected progress to be reported")
+	}
+	for file, p := range progress {
+		if p.ModelCode != "Xenova/bge-small-en-v1.5" {
+			t.Errorf("%s: unexpected model code %q", file, p.ModelCode)
+		}
+		if p.Total == 0 || p.Downloaded != p.Total {
+			t.Errorf("%s: expected a complete download, got %d of %d bytes", file, p.Downloaded, p.Total)
+		}
+	}
+	progress = make(map[string]DownloadProgress)
+	mu.Unlock()
+
+	models, err := ListCachedModels(cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to list cached models: %v", err)
+	}
+	if len(models) != 1 || models[0].Kind != KindTextEmbedding {
+		t.Fatalf("Expected the prefetched model in the cache, got %v", models)
+	}
+
+	// Cached files are reported as complete without downloading
+	if err := Prefetch(KindTextEmbedding, "BGESmallENV15", WithCacheDir(cacheDir), WithOffline(true), record); err != nil {
+		t.Fatalf("Failed to prefetch cached model offline: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(progress) != len(models[0].Files) {
+		t.Errorf("Expected progress for %d cached files, got %d", len(models[0].Files), len(progress))
+	}
+}
+
+func TestPrefetch_Offline(t *testing.T) {
+	err := Prefetch(KindTextRerank, "", WithCacheDir(t.TempDir()), WithOffline(true))
+	if !errors.Is(err, ErrModelNotCached) {
+		t.Errorf("Expected ErrModelNotCached, got %v", err)
+	}
+}
+
+func TestPrefetch_UnknownKind(t *testing.T) {
+	if err := Prefetch(KindUnknown, "BGESmallENV15", WithOffline(true)); err == nil {
+		t.Error("Expected an error for an unknown model kind")
+	}
+}
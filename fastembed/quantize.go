@@ -0,0 +1,236 @@
+package fastembed
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Compact encodings of dense embeddings for storage and search. Float16
+// halves the size with little loss; int8 quarters it; binary keeps one sign
+// bit per dimension. Each encoding has a matching similarity function that
+// works on the encoded vectors directly. The similarity functions sit in
+// search loops, so instead of returning an error they panic when the two
+// vectors differ in length, which means they come from different models or
+// encodings.
+
+// EncodeFloat16 converts a vector to IEEE 754 half precision, rounding to
+// the nearest value. Magnitudes above 65504 become infinite.
+func EncodeFloat16(v []float32) []uint16 {
+	h := make([]uint16, len(v))
+	for i, f := range v {
+		h[i] = float16Bits(f)
+	}
+	return h
+}
+
+// DecodeFloat16 converts a half precision vector back to float32
+func DecodeFloat16(h []uint16) []float32 {
+	v := make([]float32, len(h))
+	for i, b := range h {
+		v[i] = float16Value(b)
+	}
+	return v
+}
+
+// DotFloat16 returns the dot product of two half precision vectors. It
+// panics if they differ in length.
+func DotFloat16(a, b []uint16) float32 {
+	checkLengths("DotFloat16", len(a), len(b))
+	var sum float32
+	for i := range a {
+		sum += float16Value(a[i]) * float16Value(b[i])
+	}
+	return sum
+}
+
+// Int8Calibration maps float32 values to int8 with one symmetric scale for
+// all dimensions, so that int8 dot products stay proportional to float32
+// ones. Compare only vectors quantized with the same calibration.
+type Int8Calibration struct {
+	Scale float32 // Value of one int8 step
+}
+
+// CalibrateInt8 derives the calibration from a sample of embeddings, e.g. a
+// few thousand vectors of the corpus, so that the largest magnitude in the
+// sample maps to 127. Larger values in other vectors are clamped.
+func CalibrateInt8(sample [][]float32) Int8Calibration {
+	var maxAbs float32
+	for _, v := range sample {
+		for _, f := range v {
+			maxAbs = max(maxAbs, float32(math.Abs(float64(f))))
+		}
+	}
+	if maxAbs == 0 {
+		return Int8Calibration{Scale: 1}
+	}
+	return Int8Calibration{Scale: maxAbs / 127}
+}
+
+// Quantize converts a vector to int8
+func (c Int8Calibration) Quantize(v []float32) []int8 {
+	q := make([]int8, len(v))
+	for i, f := range v {
+		q[i] = int8(max(-127, min(127, math.Round(float64(f/c.Scale)))))
+	}
+	return q
+}
+
+// Dequantize converts an int8 vector back to approximate float32 values
+func (c Int8Calibration) Dequantize(q []int8) []float32 {
+	v := make([]float32, len(q))
+	for i, x := range q {
+		v[i] = float32(x) * c.Scale
+	}
+	return v
+}
+
+// Dot returns the approximate float32 dot product of two int8 vectors
+// quantized with c. It panics if they differ in length.
+func (c Int8Calibration) Dot(a, b []int8) float32 {
+	return float32(DotInt8(a, b)) * c.Scale * c.Scale
+}
+
+// DotInt8 returns the dot product of two int8 vectors. It is exact for
+// vectors of up to 133144 dimensions. It panics if they differ in length.
+func DotInt8(a, b []int8) int32 {
+	checkLengths("DotInt8", len(a), len(b))
+	var sum int32
+	for i := range a {
+		sum += int32(a[i]) * int32(b[i])
+	}
+	return sum
+}
+
+// EncodeBinary packs the sign of each dimension into one bit, set for
+// positive values. Bits are packed most significant first, so dimension i is
+// bit 7 - i%8 of byte i/8, as numpy.packbits does. The result has
+// BinarySize(len(v)) bytes.
+func EncodeBinary(v []float32) []byte {
+	b := make([]byte, BinarySize(len(v)))
+	for i, f := range v {
+		if f > 0 {
+			b[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return b
+}
+
+// BinarySize returns the bytes of a binary encoded vector of dim dimensions
+func BinarySize(dim int) int {
+	return (dim + 7) / 8
+}
+
+// Hamming returns the number of bits that differ between two binary encoded
+// vectors. A lower distance means more similar vectors. It panics if they
+// differ in length.
+func Hamming(a, b []byte) int {
+	checkLengths("Hamming", len(a), len(b))
+	n := 0
+	i := 0
+	for ; i+8 <= len(a); i += 8 {
+		n += bits.OnesCount64(binary.LittleEndian.Uint64(a[i:]) ^ binary.LittleEndian.Uint64(b[i:]))
+	}
+	for ; i < len(a); i++ {
+		n += bits.OnesCount8(a[i] ^ b[i])
+	}
+	return n
+}
+
+// checkLengths panics with a clear message if the vectors passed to a
+// similarity function differ in length
+func checkLengths(function string, a, b int) {
+	if a != b {
+		panic(fmt.Sprintf("fastembed: %s of vectors with lengths %d and %d", function, a, b))
+	}
+}
+
+// Float16 encodes all embeddings to half precision, row after row with a
+// stride of Dim
+func (d DenseEmbeddings) Float16() []uint16 {
+	return EncodeFloat16(d.Data)
+}
+
+// Int8 quantizes all embeddings with c, row after row with a stride of Dim
+func (d DenseEmbeddings) Int8(c Int8Calibration) []int8 {
+	return c.Quantize(d.Data)
+}
+
+// Binary encodes all embeddings to sign bits, row after row with a stride
+// of BinarySize(Dim) bytes
+func (d DenseEmbeddings) Binary() []byte {
+	stride := BinarySize(d.Dim)
+	b := make([]byte, 0, d.Len()*stride)
+	for i := 0; i < d.Len(); i++ {
+		b = append(b, EncodeBinary(d.Row(i))...)
+	}
+	return b
+}
+
+// float16Bits converts f to the bits of the nearest half precision value,
+// rounding ties to even
+func float16Bits(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23) & 0xff
+	mant := b & 0x7fffff
+
+	if exp == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00 // NaN
+		}
+		return sign | 0x7c00 // Infinity
+	}
+
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		return sign | 0x7c00
+	}
+
+	var shift uint
+	var m uint32
+	if e <= 0 {
+		// Subnormal: the implicit leading bit becomes part of the mantissa
+		shift = uint(14 - e)
+		if shift > 24 {
+			return sign
+		}
+		m = mant | 0x800000
+	} else {
+		shift = 13
+		m = mant
+	}
+
+	half := uint32(1) << (shift - 1)
+	rest := m & (half<<1 - 1)
+	m >>= shift
+	if rest > half || (rest == half && m&1 == 1) {
+		// Rounding up may carry into the exponent, which is still correct
+		m++
+	}
+	if e <= 0 {
+		return sign | uint16(m)
+	}
+	return sign | uint16(uint32(e)<<10+m)
+}
+
+// float16Value converts half precision bits to float32
+func float16Value(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0:
+		// Subnormal or zero: mant * 2^-24
+		v := float32(mant) / (1 << 24)
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | mant<<13)
+}
@@ -0,0 +1,108 @@
+package fastembed
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFloat16(t *testing.T) {
+	for f, want := range map[float32]uint16{
+		0:                    0x0000,
+		1:                    0x3c00,
+		-2:                   0xc000,
+		0.1:                  0x2e66,
+		65504:                0x7bff,
+		65520:                0x7c00, // Rounds to infinity
+		float32(math.Inf(1)): 0x7c00,
+		1.0 / (1 << 24):      0x0001, // Smallest subnormal
+		1.0 / (1 << 26):      0x0000,
+		6.1e-5:               0x03ff, // Rounds to the largest subnormal
+	} {
+		if got := float16Bits(f); got != want {
+			t.Errorf("float16Bits(%g) = %#04x, expected %#04x", f, got, want)
+		}
+	}
+	if got := float16Bits(float32(math.NaN())); got&0x7c00 != 0x7c00 || got&0x3ff == 0 {
+		t.Errorf("Expected NaN bits, got %#04x", got)
+	}
+
+	v := []float32{0.5, -0.25, 0.1, 3.14159, -1e-6}
+	decoded := DecodeFloat16(EncodeFloat16(v))
+	for i := range v {
+		if diff := math.Abs(float64(decoded[i] - v[i])); diff > math.Abs(float64(v[i]))/1000+1e-7 {
+			t.Errorf("Round trip of %g gave %g", v[i], decoded[i])
+		}
+	}
+	if got := DotFloat16(EncodeFloat16([]float32{1, 2}), EncodeFloat16([]float32{3, 4})); got != 11 {
+		t.Errorf("Expected a dot product of 11, got %f", got)
+	}
+}
+
+func TestInt8Calibration(t *testing.T) {
+	a := []float32{0.5, -0.25, 0.1}
+	b := []float32{-0.1, 0.4, 0.2}
+	c := CalibrateInt8([][]float32{a, b})
+	if c.Scale != 0.5/127 {
+		t.Fatalf("Expected the largest magnitude to map to 127, got scale %f", c.Scale)
+	}
+
+	qa, qb := c.Quantize(a), c.Quantize(b)
+	if qa[0] != 127 || qa[1] != -64 {
+		t.Errorf("Unexpected quantized vector %v", qa)
+	}
+	want := a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+	if got := c.Dot(qa, qb); math.Abs(float64(got-want)) > 0.01 {
+		t.Errorf("Expected a dot product near %f, got %f", want, got)
+	}
+	if got := c.Quantize([]float32{2, -2}); got[0] != 127 || got[1] != -127 {
+		t.Errorf("Expected values outside the sample range to be clamped, got %v", got)
+	}
+	if CalibrateInt8(nil).Scale != 1 {
+		t.Error("Expected a usable scale for an empty sample")
+	}
+}
+
+func TestBinary(t *testing.T) {
+	v := []float32{1, -1, 0, 2, -3, 4, 5, -6, 7}
+	b := EncodeBinary(v)
+	if len(b) != 2 || b[0] != 0b10010110 || b[1] != 0b10000000 {
+		t.Fatalf("Unexpected packed bits %08b", b)
+	}
+
+	long := make([]float32, 100)
+	other := make([]float32, 100)
+	for i := range long {
+		long[i] = 1
+		other[i] = 1
+		if i%10 == 0 {
+			other[i] = -1
+		}
+	}
+	if got := Hamming(EncodeBinary(long), EncodeBinary(other)); got != 10 {
+		t.Errorf("Expected a Hamming distance of 10, got %d", got)
+	}
+
+	dense := DenseEmbeddings{Data: append(append([]float32{}, v...), v...), Dim: len(v)}
+	if packed := dense.Binary(); len(packed) != 4 || packed[2] != b[0] {
+		t.Errorf("Expected rows with a stride of %d bytes, got %08b", BinarySize(len(v)), packed)
+	}
+}
+
+func TestSimilarity_LengthMismatch(t *testing.T) {
+	for name, f := range map[string]func(){
+		"DotFloat16": func() { DotFloat16(make([]uint16, 3), make([]uint16, 2)) },
+		"DotInt8":    func() { DotInt8(make([]int8, 2), make([]int8, 3)) },
+		"Hamming":    func() { Hamming(make([]byte, 9), make([]byte, 8)) },
+	} {
+		func() {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, name) {
+					t.Errorf("Expected %s to panic naming itself, got %q", name, msg)
+				}
+			}()
+			f()
+		}()
+	}
+}
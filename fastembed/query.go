@@ -0,0 +1,32 @@
+package fastembed
+
+import "context"
+
+// EmbedQuery embeds search queries. Each query gets the prefix the model
+// expects for queries, e.g. "query: " for E5 models, so that it matches
+// passages embedded with EmbedPassage. Use WithQueryPrefix to override the
+// prefix; Options().QueryPrefix reports the one in use.
+func (te *TextEmbedding) EmbedQuery(ctx context.Context, queries []string, opts ...CallOption) ([][]float32, error) {
+	return te.EmbedContext(ctx, addPrefix(te.options.QueryPrefix, queries), opts...)
+}
+
+// EmbedPassage embeds documents and passages to be searched with EmbedQuery.
+// Each passage gets the prefix the model expects for passages, e.g.
+// "passage: " for E5 models; many models use none. Use WithPassagePrefix to
+// override the prefix.
+func (te *TextEmbedding) EmbedPassage(ctx context.Context, passages []string, opts ...CallOption) ([][]float32, error) {
+	return te.EmbedContext(ctx, addPrefix(te.options.PassagePrefix, passages), opts...)
+}
+
+// addPrefix returns texts with prefix prepended, or texts itself if the
+// prefix is empty
+func addPrefix(prefix string, texts []string) []string {
+	if prefix == "" {
+		return texts
+	}
+	prefixed := make([]string, len(texts))
+	for i, text := range texts {
+		prefixed[i] = prefix + text
+	}
+	return prefixed
+}
@@ -0,0 +1,56 @@
+package fastembed
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAddPrefix(t *testing.T) {
+	texts := []string{"a", "b"}
+	if got := addPrefix("", texts); &got[0] != &texts[0] {
+		t.Error("Expected an empty prefix to return the texts unchanged")
+	}
+	got := addPrefix("query: ", texts)
+	if got[0] != "query: a" || got[1] != "query: b" || texts[0] != "a" {
+		t.Errorf("Unexpected prefixed texts %q from %q", got, texts)
+	}
+}
+
+func TestTextEmbedding_EmbedQuery(t *testing.T) {
+	model, err := NewTextEmbedding("BGESmallENV15")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer model.Close()
+
+	prefix := model.Options().QueryPrefix
+	if !strings.HasPrefix(prefix, "Represent this sentence") || model.Options().PassagePrefix != "" {
+		t.Fatalf("Unexpected prefixes for %s: %+v", model.ModelCode(), model.Options())
+	}
+
+	ctx := context.Background()
+	query, err := model.EmbedQuery(ctx, []string{"capital of France"})
+	if err != nil {
+		t.Fatalf("Failed to embed query: %v", err)
+	}
+	prefixed, err := model.EmbedContext(ctx, []string{prefix + "capital of France"})
+	if err != nil {
+		t.Fatalf("Failed to embed prefixed query: %v", err)
+	}
+	for i := range query[0] {
+		if diff := query[0][i] - prefixed[0][i]; diff > 1e-4 || diff < -1e-4 {
+			t.Fatalf("Expected EmbedQuery to add the model's query prefix")
+		}
+	}
+
+	// An empty override disables the prefix
+	plain, err := NewTextEmbedding("BGESmallENV15", WithQueryPrefix(""))
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer plain.Close()
+	if plain.Options().QueryPrefix != "" {
+		t.Errorf("Expected the query prefix to be overridden, got %q", plain.Options().QueryPrefix)
+	}
+}
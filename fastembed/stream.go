@@ -0,0 +1,285 @@
+package fastembed
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// defaultStreamBatchSize is the batch size of streams without WithBatchSize,
+// matching the fastembed-rs default
+const defaultStreamBatchSize = 256
+
+// Source produces the inputs of a stream. It calls yield for every input in
+// order and stops early, returning nil, when yield returns false. A non-nil
+// error ends the stream after the inputs yielded so far.
+type Source func(ctx context.Context, yield func(input string) bool) error
+
+// SliceSource returns a Source yielding the given inputs
+func SliceSource(inputs []string) Source {
+	return func(ctx context.Context, yield func(string) bool) error {
+		for _, input := range inputs {
+			if !yield(input) {
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// ChannelSource returns a Source yielding inputs received from ch until it
+// is closed
+func ChannelSource(ch <-chan string) Source {
+	return func(ctx context.Context, yield func(string) bool) error {
+		for {
+			select {
+			case input, ok := <-ch:
+				if !ok || !yield(input) {
+					return nil
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// SeqSource returns a Source yielding the inputs of an iterator function,
+// such as an iter.Seq[string]
+func SeqSource(seq func(yield func(string) bool)) Source {
+	return func(ctx context.Context, yield func(string) bool) error {
+		seq(yield)
+		return nil
+	}
+}
+
+// LineSource returns a Source yielding the lines of r without their line
+// endings. Lines may be of any length.
+func LineSource(r io.Reader) Source {
+	return func(ctx context.Context, yield func(string) bool) error {
+		br := bufio.NewReader(r)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			if len(line) > 0 {
+				line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+				if !yield(line) {
+					return nil
+				}
+			}
+			if err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// StreamResult is one result of a stream. Index is the position of the
+// input in the source. A result with a negative Index carries the error of
+// the Source and is the last result of the stream.
+type StreamResult[R any] struct {
+	Index     int
+	Embedding R
+	Err       error
+}
+
+// EmbedStream embeds the texts of src in batches and yields one result per
+// text. Only the batches being embedded, plus WithBufferSize results, are
+// held in memory: the stream stops reading src while the consumer falls
+// behind. Results come in input order unless WithOrdered(false) is given,
+// and WithConcurrency embeds several batches at once. A batch that fails
+// yields its error for each of its texts and the stream continues. Options
+// of EmbedContext, such as WithSkipInvalid, apply to every batch, and input
+// positions in errors are positions in the stream.
+//
+// The channel is closed when the stream ends. When ctx is done the stream
+// stops early without a final error; check ctx.Err() to tell it apart from
+// a finished stream. The channel must be read until it is closed or ctx is
+// done.
+func (te *TextEmbedding) EmbedStream(ctx context.Context, src Source, opts ...CallOption) <-chan StreamResult[[]float32] {
+	return embedStream(ctx, src, opts, func(ctx context.Context, texts []string, opts []CallOption) ([][]float32, error) {
+		return te.EmbedContext(ctx, texts, opts...)
+	})
+}
+
+// EmbedStream embeds the texts of src in batches and yields one result per
+// text. See TextEmbedding.EmbedStream for details.
+func (ste *SparseTextEmbedding) EmbedStream(ctx context.Context, src Source, opts ...CallOption) <-chan StreamResult[SparseEmbedding] {
+	return embedStream(ctx, src, opts, func(ctx context.Context, texts []string, opts []CallOption) ([]SparseEmbedding, error) {
+		return ste.EmbedContext(ctx, texts, opts...)
+	})
+}
+
+// EmbedStream embeds the image paths of src in batches and yields one
+// result per image. See TextEmbedding.EmbedStream for details.
+func (ie *ImageEmbedding) EmbedStream(ctx context.Context, src Source, opts ...CallOption) <-chan StreamResult[[]float32] {
+	return embedStream(ctx, src, opts, func(ctx context.Context, imagePaths []string, opts []CallOption) ([][]float32, error) {
+		return ie.EmbedContext(ctx, imagePaths, opts...)
+	})
+}
+
+// streamBatch is a batch of inputs read from a stream source
+type streamBatch[R any] struct {
+	start      int
+	inputs     []string
+	embeddings []R
+	err        error
+	done       chan struct{}
+}
+
+// embedStream runs a stream. embed is called for each batch with the
+// options of the stream, so call options such as WithSkipInvalid apply to
+// every batch.
+func embedStream[R any](ctx context.Context, src Source, opts []CallOption, embed func(context.Context, []string, []CallOption) ([]R, error)) <-chan StreamResult[R] {
+	o := newCallOptions(opts)
+	batchSize := o.batchSize
+	if batchSize <= 0 {
+		batchSize = defaultStreamBatchSize
+	}
+	concurrency := o.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	bufferSize := o.bufferSize
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	batchOpts := append(opts[:len(opts):len(opts)], WithBatchSize(batchSize))
+
+	out := make(chan StreamResult[R], bufferSize)
+	jobs := make(chan *streamBatch[R], concurrency)
+	// Batches in source order, for ordered streams
+	var pending chan *streamBatch[R]
+	if o.ordered {
+		pending = make(chan *streamBatch[R], concurrency)
+	}
+
+	emit := func(b *streamBatch[R]) {
+		for i := range b.inputs {
+			res := StreamResult[R]{Index: b.start + i, Err: b.err}
+			if b.err == nil {
+				res.Embedding = b.embeddings[i]
+			}
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	var srcErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		if pending != nil {
+			defer close(pending)
+		}
+
+		batch := &streamBatch[R]{}
+		send := func() bool {
+			b := batch
+			batch = &streamBatch[R]{start: b.start + len(b.inputs)}
+			b.done = make(chan struct{})
+			select {
+			case jobs <- b:
+			case <-ctx.Done():
+				return false
+			}
+			if pending != nil {
+				select {
+				case pending <- b:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		srcErr = src(ctx, func(input string) bool {
+			batch.inputs = append(batch.inputs, input)
+			if len(batch.inputs) == batchSize {
+				return send()
+			}
+			return ctx.Err() == nil
+		})
+		if len(batch.inputs) > 0 && ctx.Err() == nil {
+			send()
+		}
+	}()
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range jobs {
+				b.embeddings, b.err = embed(ctx, b.inputs, batchOpts)
+				b.err = offsetInputs(b.err, b.start)
+				if b.err == nil && len(b.embeddings) != len(b.inputs) {
+					b.err = &Error{message: "embedding count does not match input count", code: ErrorInference}
+				}
+				close(b.done)
+				if pending == nil {
+					emit(b)
+				}
+			}
+		}()
+	}
+
+	if pending != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range pending {
+				select {
+				case <-b.done:
+				case <-ctx.Done():
+					continue
+				}
+				emit(b)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		if srcErr != nil && ctx.Err() == nil {
+			select {
+			case out <- StreamResult[R]{Index: -1, Err: srcErr}:
+			case <-ctx.Done():
+			}
+		}
+		close(out)
+	}()
+	return out
+}
+
+// offsetInputs shifts the input positions in err, which are relative to a
+// batch, by the position of the batch in the stream
+func offsetInputs(err error, offset int) error {
+	var inputErr *InputError
+	if errors.As(err, &inputErr) {
+		shifted := &InputError{Inputs: make([]InvalidInput, len(inputErr.Inputs))}
+		for i, input := range inputErr.Inputs {
+			if input.Index >= 0 {
+				input.Index += offset
+			}
+			shifted.Inputs[i] = input
+		}
+		return shifted
+	}
+	var e *Error
+	if errors.As(err, &e) && e.input > 0 {
+		shifted := *e
+		shifted.input += offset
+		return &shifted
+	}
+	return err
+}
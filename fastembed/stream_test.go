@@ -0,0 +1,217 @@
+package fastembed
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// lengthEmbed embeds texts as their lengths and fails batches containing
+// "fail"
+func lengthEmbed(ctx context.Context, texts []string, opts []CallOption) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]int, len(texts))
+	for i, text := range texts {
+		if text == "fail" {
+			return nil, errors.New("batch failed")
+		}
+		out[i] = len(text)
+	}
+	return out, nil
+}
+
+// validatingEmbed embeds texts as their lengths after validating them the
+// way the models do
+func validatingEmbed(ctx context.Context, texts []string, opts []CallOption) ([]int, error) {
+	valid, kept, err := validateInputs(texts, newCallOptions(opts).skipInvalid, checkText)
+	if err != nil {
+		return nil, err
+	}
+	out, err := lengthEmbed(ctx, valid, opts)
+	if err != nil {
+		return nil, err
+	}
+	return spread(out, kept, len(texts)), nil
+}
+
+func collectStream[R any](t *testing.T, results <-chan StreamResult[R]) []StreamResult[R] {
+	t.Helper()
+	var all []StreamResult[R]
+	for res := range results {
+		all = append(all, res)
+	}
+	return all
+}
+
+func TestLineSource(t *testing.T) {
+	var lines []string
+	err := LineSource(strings.NewReader("one\r\ntwo\n\nthree"))(context.Background(), func(line string) bool {
+		lines = append(lines, line)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Failed to read lines: %v", err)
+	}
+	if strings.Join(lines, "|") != "one|two||three" {
+		t.Errorf("Unexpected lines: %q", lines)
+	}
+}
+
+func TestEmbedStream_Ordered(t *testing.T) {
+	inputs := []string{"a", "bb", "ccc", "dddd", "eeeee", "ffffff", "g"}
+	results := collectStream(t, embedStream(context.Background(), SliceSource(inputs),
+		[]CallOption{WithBatchSize(2), WithConcurrency(3)}, lengthEmbed))
+
+	if len(results) != len(inputs) {
+		t.Fatalf("Expected %d results, got %d", len(inputs), len(results))
+	}
+	for i, res := range results {
+		if res.Index != i || res.Err != nil || res.Embedding != len(inputs[i]) {
+			t.Errorf("Unexpected result %d: %+v", i, res)
+		}
+	}
+}
+
+func TestEmbedStream_Unordered(t *testing.T) {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for i := 0; i < 100; i++ {
+			ch <- strings.Repeat("x", i)
+		}
+	}()
+
+	results := collectStream(t, embedStream(context.Background(), ChannelSource(ch),
+		[]CallOption{WithBatchSize(7), WithConcurrency(4), WithOrdered(false)}, lengthEmbed))
+
+	seen := make(map[int]bool)
+	for _, res := range results {
+		if res.Err != nil || res.Embedding != res.Index {
+			t.Errorf("Unexpected result: %+v", res)
+		}
+		seen[res.Index] = true
+	}
+	if len(seen) != 100 {
+		t.Errorf("Expected 100 distinct results, got %d", len(seen))
+	}
+}
+
+func TestEmbedStream_Errors(t *testing.T) {
+	readErr := errors.New("read failed")
+	src := func(ctx context.Context, yield func(string) bool) error {
+		for _, s := range []string{"a", "fail", "ccc"} {
+			if !yield(s) {
+				return nil
+			}
+		}
+		return readErr
+	}
+
+	results := collectStream(t, embedStream(context.Background(), src, []CallOption{WithBatchSize(2)}, lengthEmbed))
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %+v", results)
+	}
+	if results[0].Err == nil || results[1].Err == nil {
+		t.Error("Expected the failed batch to report its error per text")
+	}
+	if results[2].Index != 2 || results[2].Err != nil || results[2].Embedding != 3 {
+		t.Errorf("Expected the stream to continue after a failed batch, got %+v", results[2])
+	}
+	if results[3].Index != -1 || !errors.Is(results[3].Err, readErr) {
+		t.Errorf("Expected the source error last, got %+v", results[3])
+	}
+}
+
+func TestEmbedStream_InvalidInput(t *testing.T) {
+	inputs := []string{"a", "bb", "ccc", "\xff", "eeeee"}
+
+	results := collectStream(t, embedStream(context.Background(), SliceSource(inputs), []CallOption{WithBatchSize(2)}, validatingEmbed))
+	if len(results) != len(inputs) {
+		t.Fatalf("Expected %d results, got %d", len(inputs), len(results))
+	}
+	var inputErr *InputError
+	if !errors.As(results[3].Err, &inputErr) || inputErr.Inputs[0].Index != 3 {
+		t.Errorf("Expected an input error at stream position 3, got %v", results[3].Err)
+	}
+	if results[1].Err != nil || results[4].Err != nil {
+		t.Error("Expected only the batch of the invalid input to fail")
+	}
+
+	results = collectStream(t, embedStream(context.Background(), SliceSource(inputs),
+		[]CallOption{WithBatchSize(2), WithSkipInvalid(true)}, validatingEmbed))
+	for i, res := range results {
+		if res.Err != nil {
+			t.Errorf("Expected WithSkipInvalid to apply to every batch, got %v", res.Err)
+		}
+		if want := len(inputs[i]); i != 3 && res.Embedding != want {
+			t.Errorf("Expected %d for input %d, got %d", want, i, res.Embedding)
+		}
+	}
+	if results[3].Embedding != 0 {
+		t.Errorf("Expected a zero result for the skipped input, got %d", results[3].Embedding)
+	}
+}
+
+func TestEmbedStream_Backpressure(t *testing.T) {
+	var read atomic.Int64
+	src := func(ctx context.Context, yield func(string) bool) error {
+		for {
+			read.Add(1)
+			if !yield("x") {
+				return nil
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := embedStream(ctx, src, []CallOption{WithBatchSize(10)}, lengthEmbed)
+	<-results
+	time.Sleep(50 * time.Millisecond)
+
+	// The reader stops once the pipeline is full instead of reading forever
+	if n := read.Load(); n > 100 {
+		t.Errorf("Expected the stream to stop reading while the consumer is idle, read %d", n)
+	}
+
+	cancel()
+	for range results {
+	}
+}
+
+func TestTextEmbedding_EmbedStream(t *testing.T) {
+	model, err := NewTextEmbedding("")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer model.Close()
+
+	corpus := strings.NewReader("Hello, World!\nThis is a test.\nStreaming works.\n")
+	count := 0
+	for res := range model.EmbedStream(context.Background(), LineSource(corpus), WithBatchSize(2)) {
+		if res.Err != nil {
+			t.Fatalf("Failed to embed line %d: %v", res.Index, res.Err)
+		}
+		if res.Index != count || len(res.Embedding) != model.Dimension() {
+			t.Errorf("Unexpected result %d with %d dimensions", res.Index, len(res.Embedding))
+		}
+		count++
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 results, got %d", count)
+	}
+
+	texts := SliceSource([]string{"Hello", "World", "\xff", "Streaming works."})
+	for res := range model.EmbedStream(context.Background(), texts, WithBatchSize(2), WithSkipInvalid(true)) {
+		if res.Err != nil {
+			t.Fatalf("Failed to embed text %d: %v", res.Index, res.Err)
+		}
+		if skipped := res.Index == 2; skipped != (res.Embedding == nil) {
+			t.Errorf("Expected only the invalid text to be skipped, got %d dimensions for text %d", len(res.Embedding), res.Index)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package fastembed
+
+/*
+#include "fastembed.h"
+*/
+import "C"
+import "unsafe"
+
+// Tokens is a text as the model's tokenizer splits it, including special
+// tokens such as [CLS] and [SEP]
+type Tokens struct {
+	IDs       []uint32
+	Tokens    []string
+	Offsets   [][2]int // Byte range of each token in the text; special tokens have empty ranges
+	Truncated bool     // Whether the model cuts the text to its max length when embedding it
+}
+
+// Tokenize splits text into tokens with the model's tokenizer. Unlike
+// embedding, it does not truncate: all tokens of the text are returned and
+// Truncated reports whether embedding would cut them to MaxLength.
+func (te *TextEmbedding) Tokenize(text string) (Tokens, error) {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+	if te.handle == nil {
+		return Tokens{}, ErrClosed
+	}
+	return tokenize(text, te.modelCode, te.MaxLength(), func(p packedStrings, cErr **C.FastEmbedError) *C.FastEmbedTokens {
+		return C.fastembed_text_embedding_tokenize(te.handle, p.dataPtr(), C.size_t(len(text)), cErr)
+	})
+}
+
+// CountTokens returns the number of tokens of each text, including special
+// tokens and without truncation, e.g. to plan chunks of a long document
+func (te *TextEmbedding) CountTokens(texts []string) ([]int, error) {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+	if te.handle == nil {
+		return nil, ErrClosed
+	}
+	return countTokens(texts, te.modelCode, func(p packedStrings, counts *C.size_t, cErr **C.FastEmbedError) C.bool {
+		return C.fastembed_text_embedding_count_tokens(te.handle, p.dataPtr(), p.offsetsPtr(), p.count(), counts, cErr)
+	})
+}
+
+// Tokenize splits text into tokens with the model's tokenizer, like
+// TextEmbedding.Tokenize
+func (ste *SparseTextEmbedding) Tokenize(text string) (Tokens, error) {
+	ste.mu.RLock()
+	defer ste.mu.RUnlock()
+	if ste.handle == nil {
+		return Tokens{}, ErrClosed
+	}
+	return tokenize(text, ste.modelCode, ste.MaxLength(), func(p packedStrings, cErr **C.FastEmbedError) *C.FastEmbedTokens {
+		return C.fastembed_sparse_text_embedding_tokenize(ste.handle, p.dataPtr(), C.size_t(len(text)), cErr)
+	})
+}
+
+// CountTokens returns the number of tokens of each text, like
+// TextEmbedding.CountTokens
+func (ste *SparseTextEmbedding) CountTokens(texts []string) ([]int, error) {
+	ste.mu.RLock()
+	defer ste.mu.RUnlock()
+	if ste.handle == nil {
+		return nil, ErrClosed
+	}
+	return countTokens(texts, ste.modelCode, func(p packedStrings, counts *C.size_t, cErr **C.FastEmbedError) C.bool {
+		return C.fastembed_sparse_text_embedding_count_tokens(ste.handle, p.dataPtr(), p.offsetsPtr(), p.count(), counts, cErr)
+	})
+}
+
+// Tokenize splits text into tokens with the model's tokenizer, like
+// TextEmbedding.Tokenize. Truncated reports whether the text alone exceeds
+// MaxLength; a query and document are truncated together when reranked.
+func (tr *TextRerank) Tokenize(text string) (Tokens, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	if tr.handle == nil {
+		return Tokens{}, ErrClosed
+	}
+	return tokenize(text, tr.modelCode, tr.MaxLength(), func(p packedStrings, cErr **C.FastEmbedError) *C.FastEmbedTokens {
+		return C.fastembed_text_rerank_tokenize(tr.handle, p.dataPtr(), C.size_t(len(text)), cErr)
+	})
+}
+
+// CountTokens returns the number of tokens of each text, like
+// TextEmbedding.CountTokens
+func (tr *TextRerank) CountTokens(texts []string) ([]int, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	if tr.handle == nil {
+		return nil, ErrClosed
+	}
+	return countTokens(texts, tr.modelCode, func(p packedStrings, counts *C.size_t, cErr **C.FastEmbedError) C.bool {
+		return C.fastembed_text_rerank_count_tokens(tr.handle, p.dataPtr(), p.offsetsPtr(), p.count(), counts, cErr)
+	})
+}
+
+// tokenize validates text, passes it to call packed and converts the
+// resulting tokens
+func tokenize(text, model string, maxLength int, call func(packedStrings, **C.FastEmbedError) *C.FastEmbedTokens) (Tokens, error) {
+	if reason := checkText(text); reason != "" {
+		return Tokens{}, &InputError{Inputs: []InvalidInput{{Index: 0, Reason: reason}}}
+	}
+
+	var cErr *C.FastEmbedError
+	cTokens := call(packStrings([]string{text}), &cErr)
+	if cTokens == nil {
+		return Tokens{}, newModelError(model, cErr)
+	}
+	defer C.fastembed_tokens_free(cTokens)
+
+	n := int(cTokens.len)
+	ids := unsafe.Slice((*uint32)(unsafe.Pointer(cTokens.ids)), n)
+	offsets := unsafe.Slice(cTokens.offsets, 2*n)
+	tokenOffsets := unsafe.Slice(cTokens.token_offsets, n+1)
+	data := unsafe.Slice((*byte)(unsafe.Pointer(cTokens.tokens)), int(tokenOffsets[n]))
+
+	tokens := Tokens{
+		IDs:       append([]uint32(nil), ids...),
+		Tokens:    make([]string, n),
+		Offsets:   make([][2]int, n),
+		Truncated: maxLength > 0 && n > maxLength,
+	}
+	for i := range tokens.Tokens {
+		tokens.Tokens[i] = string(data[tokenOffsets[i]:tokenOffsets[i+1]])
+		tokens.Offsets[i] = [2]int{int(offsets[2*i]), int(offsets[2*i+1])}
+	}
+	return tokens, nil
+}
+
+// countTokens validates texts and lets call count their tokens
+func countTokens(texts []string, model string, call func(packedStrings, *C.size_t, **C.FastEmbedError) C.bool) ([]int, error) {
+	if _, _, err := validateInputs(texts, false, checkText); err != nil {
+		return nil, err
+	}
+	if len(texts) == 0 {
+		return []int{}, nil
+	}
+
+	counts := make([]C.size_t, len(texts))
+	var cErr *C.FastEmbedError
+	if !call(packStrings(texts), &counts[0], &cErr) {
+		return nil, newModelError(model, cErr)
+	}
+
+	result := make([]int, len(texts))
+	for i, c := range counts {
+		result[i] = int(c)
+	}
+	return result, nil
+}
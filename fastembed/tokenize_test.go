@@ -0,0 +1,79 @@
+package fastembed
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTextEmbedding_Tokenize(t *testing.T) {
+	model, err := NewTextEmbedding("BGESmallENV15", WithMaxLength(16))
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer model.Close()
+
+	text := "Hello, World!"
+	tokens, err := model.Tokenize(text)
+	if err != nil {
+		t.Fatalf("Failed to tokenize: %v", err)
+	}
+	if len(tokens.IDs) != len(tokens.Tokens) || len(tokens.IDs) != len(tokens.Offsets) {
+		t.Fatalf("Expected one ID and offset per token, got %+v", tokens)
+	}
+	if tokens.Tokens[0] != "[CLS]" || tokens.Tokens[len(tokens.Tokens)-1] != "[SEP]" {
+		t.Errorf("Expected special tokens around the text, got %q", tokens.Tokens)
+	}
+	if first := tokens.Offsets[1]; text[first[0]:first[1]] != "Hello" {
+		t.Errorf("Expected the first word to cover %q, got %v", "Hello", first)
+	}
+	if tokens.Truncated {
+		t.Error("Expected a short text not to be truncated")
+	}
+
+	long := strings.Repeat("word ", 40)
+	counts, err := model.CountTokens([]string{text, long})
+	if err != nil {
+		t.Fatalf("Failed to count tokens: %v", err)
+	}
+	if counts[0] != len(tokens.IDs) || counts[1] <= 16 {
+		t.Errorf("Unexpected token counts %v", counts)
+	}
+	if tokens, err := model.Tokenize(long); err != nil || !tokens.Truncated || len(tokens.IDs) != counts[1] {
+		t.Errorf("Expected all tokens of a long text and the truncation flag, got %d tokens, %v", len(tokens.IDs), err)
+	}
+
+	dense, err := model.EmbedDense(context.Background(), []string{text, long}, WithReportTruncated(true))
+	if err != nil {
+		t.Fatalf("Failed to embed: %v", err)
+	}
+	if len(dense.Truncated) != 2 || dense.Truncated[0] || !dense.Truncated[1] {
+		t.Errorf("Expected only the long text to be truncated, got %v", dense.Truncated)
+	}
+	if dense, err := model.EmbedDense(context.Background(), []string{long}); err != nil || dense.Truncated != nil {
+		t.Errorf("Expected no truncation flags without WithReportTruncated, got %v, %v", dense.Truncated, err)
+	}
+}
+
+func TestTextRerank_Truncated(t *testing.T) {
+	model, err := NewTextRerank("", WithMaxLength(32))
+	if err != nil {
+		t.Fatalf("Failed to create reranker: %v", err)
+	}
+	defer model.Close()
+
+	docs := []string{"Paris is the capital of France.", strings.Repeat("France ", 50)}
+	results, err := model.RerankContext(context.Background(), "capital of France", docs, WithReportTruncated(true))
+	if err != nil {
+		t.Fatalf("Failed to rerank: %v", err)
+	}
+	for _, r := range results {
+		if r.Truncated != (r.Index == 1) {
+			t.Errorf("Unexpected truncation flag for document %d: %v", r.Index, r.Truncated)
+		}
+	}
+
+	if counts, err := model.CountTokens(docs); err != nil || counts[1] <= 32 {
+		t.Errorf("Expected the long document to exceed the max length, got %v, %v", counts, err)
+	}
+}
@@ -0,0 +1,252 @@
+package fastembed
+
+/*
+#include "fastembed.h"
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"fmt"
+	"io/fs"
+	"runtime"
+	"unsafe"
+)
+
+// Pooling selects how token embeddings of a user-defined model are combined
+// into a single vector
+type Pooling int
+
+const (
+	// PoolingDefault uses the fastembed-rs default (CLS pooling)
+	PoolingDefault Pooling = C.FASTEMBED_POOLING_DEFAULT
+	// PoolingCLS uses the embedding of the first ([CLS]) token
+	PoolingCLS Pooling = C.FASTEMBED_POOLING_CLS
+	// PoolingMean averages the token embeddings, weighted by the attention mask
+	PoolingMean Pooling = C.FASTEMBED_POOLING_MEAN
+)
+
+// TokenizerFiles holds the paths of the Hugging Face tokenizer files that
+// accompany a user-defined ONNX model
+type TokenizerFiles struct {
+	Tokenizer        string // tokenizer.json
+	Config           string // config.json
+	SpecialTokensMap string // special_tokens_map.json
+	TokenizerConfig  string // tokenizer_config.json
+}
+
+// toC converts the paths to C strings. The returned function releases them.
+func (tf TokenizerFiles) toC() (*C.FastEmbedTokenizerPaths, func()) {
+	cPaths := &C.FastEmbedTokenizerPaths{
+		tokenizer:          C.CString(tf.Tokenizer),
+		config:             C.CString(tf.Config),
+		special_tokens_map: C.CString(tf.SpecialTokensMap),
+		tokenizer_config:   C.CString(tf.TokenizerConfig),
+	}
+	return cPaths, func() {
+		C.free(unsafe.Pointer(cPaths.tokenizer))
+		C.free(unsafe.Pointer(cPaths.config))
+		C.free(unsafe.Pointer(cPaths.special_tokens_map))
+		C.free(unsafe.Pointer(cPaths.tokenizer_config))
+	}
+}
+
+// TokenizerBytes holds the contents of the Hugging Face tokenizer files that
+// accompany a user-defined ONNX model
+type TokenizerBytes struct {
+	Tokenizer        []byte // tokenizer.json
+	Config           []byte // config.json
+	SpecialTokensMap []byte // special_tokens_map.json
+	TokenizerConfig  []byte // tokenizer_config.json
+}
+
+// ReadTokenizerFiles reads the tokenizer files at the given paths from fsys,
+// e.g. an embed.FS
+func ReadTokenizerFiles(fsys fs.FS, files TokenizerFiles) (TokenizerBytes, error) {
+	var tb TokenizerBytes
+	for _, f := range []struct {
+		path string
+		dst  *[]byte
+	}{
+		{files.Tokenizer, &tb.Tokenizer},
+		{files.Config, &tb.Config},
+		{files.SpecialTokensMap, &tb.SpecialTokensMap},
+		{files.TokenizerConfig, &tb.TokenizerConfig},
+	} {
+		data, err := fs.ReadFile(fsys, f.path)
+		if err != nil {
+			return TokenizerBytes{}, fmt.Errorf("fastembed: reading tokenizer file: %w", err)
+		}
+		*f.dst = data
+	}
+	return tb, nil
+}
+
+// cBytes returns a C view of b. The backing array is pinned so the view can be
+// stored in C structs passed to Rust; the data is copied before the call returns.
+func cBytes(pinner *runtime.Pinner, b []byte) C.FastEmbedBytes {
+	if len(b) == 0 {
+		return C.FastEmbedBytes{}
+	}
+	pinner.Pin(&b[0])
+	return C.FastEmbedBytes{
+		data: (*C.uint8_t)(unsafe.Pointer(&b[0])),
+		len:  C.size_t(len(b)),
+	}
+}
+
+// toC returns C views of the tokenizer files, pinned by pinner
+func (tb TokenizerBytes) toC(pinner *runtime.Pinner) *C.FastEmbedTokenizerBytes {
+	return &C.FastEmbedTokenizerBytes{
+		tokenizer:          cBytes(pinner, tb.Tokenizer),
+		config:             cBytes(pinner, tb.Config),
+		special_tokens_map: cBytes(pinner, tb.SpecialTokensMap),
+		tokenizer_config:   cBytes(pinner, tb.TokenizerConfig),
+	}
+}
+
+// readModelFS reads an ONNX model and its tokenizer files from fsys
+func readModelFS(fsys fs.FS, onnxPath string, tokenizerFiles TokenizerFiles) ([]byte, TokenizerBytes, error) {
+	onnxModel, err := fs.ReadFile(fsys, onnxPath)
+	if err != nil {
+		return nil, TokenizerBytes{}, fmt.Errorf("fastembed: reading ONNX model: %w", err)
+	}
+	tokenizer, err := ReadTokenizerFiles(fsys, tokenizerFiles)
+	if err != nil {
+		return nil, TokenizerBytes{}, err
+	}
+	return onnxModel, tokenizer, nil
+}
+
+// NewTextEmbeddingFromFiles creates a text embedding model from a local ONNX
+// model file and its tokenizer files. Nothing is downloaded, so
+// WithCacheDir and WithShowDownloadProgress have no effect.
+func NewTextEmbeddingFromFiles(onnxPath string, tokenizerFiles TokenizerFiles, pooling Pooling, opts ...Option) (*TextEmbedding, error) {
+	var cErr *C.FastEmbedError
+	cOnnxPath := C.CString(onnxPath)
+	defer C.free(unsafe.Pointer(cOnnxPath))
+
+	cPaths, freePaths := tokenizerFiles.toC()
+	defer freePaths()
+
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	handle := C.fastembed_text_embedding_new_from_files(cOnnxPath, cPaths, C.int(pooling), cOpts, &cErr)
+	if handle == nil {
+		return nil, newError(cErr)
+	}
+
+	te := &TextEmbedding{handle: handle, modelDetails: textEmbeddingDetails(handle, o)}
+	runtime.SetFinalizer(te, func(t *TextEmbedding) {
+		t.Close()
+	})
+	return te, nil
+}
+
+// NewTextEmbeddingFromBytes creates a text embedding model from an ONNX model
+// and tokenizer files held in memory. The bytes are passed to the Rust library
+// directly and never written to disk.
+func NewTextEmbeddingFromBytes(onnxModel []byte, tokenizer TokenizerBytes, pooling Pooling, opts ...Option) (*TextEmbedding, error) {
+	var cErr *C.FastEmbedError
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	cOnnx := cBytes(&pinner, onnxModel)
+	cTokenizer := tokenizer.toC(&pinner)
+
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	handle := C.fastembed_text_embedding_new_from_bytes(&cOnnx, cTokenizer, C.int(pooling), cOpts, &cErr)
+	if handle == nil {
+		return nil, newError(cErr)
+	}
+
+	te := &TextEmbedding{handle: handle, modelDetails: textEmbeddingDetails(handle, o)}
+	runtime.SetFinalizer(te, func(t *TextEmbedding) {
+		t.Close()
+	})
+	return te, nil
+}
+
+// NewTextEmbeddingFromFS creates a text embedding model from files in fsys,
+// such as an embed.FS bundled into the binary
+func NewTextEmbeddingFromFS(fsys fs.FS, onnxPath string, tokenizerFiles TokenizerFiles, pooling Pooling, opts ...Option) (*TextEmbedding, error) {
+	onnxModel, tokenizer, err := readModelFS(fsys, onnxPath, tokenizerFiles)
+	if err != nil {
+		return nil, err
+	}
+	return NewTextEmbeddingFromBytes(onnxModel, tokenizer, pooling, opts...)
+}
+
+// NewSparseTextEmbeddingFromBytes creates a sparse text embedding model from an
+// ONNX model and tokenizer files held in memory
+func NewSparseTextEmbeddingFromBytes(onnxModel []byte, tokenizer TokenizerBytes, opts ...Option) (*SparseTextEmbedding, error) {
+	var cErr *C.FastEmbedError
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	cOnnx := cBytes(&pinner, onnxModel)
+	cTokenizer := tokenizer.toC(&pinner)
+
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	handle := C.fastembed_sparse_text_embedding_new_from_bytes(&cOnnx, cTokenizer, cOpts, &cErr)
+	if handle == nil {
+		return nil, newError(cErr)
+	}
+
+	ste := &SparseTextEmbedding{handle: handle, modelDetails: sparseTextEmbeddingDetails(handle, o)}
+	runtime.SetFinalizer(ste, func(s *SparseTextEmbedding) {
+		s.Close()
+	})
+	return ste, nil
+}
+
+// NewSparseTextEmbeddingFromFS creates a sparse text embedding model from files in fsys
+func NewSparseTextEmbeddingFromFS(fsys fs.FS, onnxPath string, tokenizerFiles TokenizerFiles, opts ...Option) (*SparseTextEmbedding, error) {
+	onnxModel, tokenizer, err := readModelFS(fsys, onnxPath, tokenizerFiles)
+	if err != nil {
+		return nil, err
+	}
+	return NewSparseTextEmbeddingFromBytes(onnxModel, tokenizer, opts...)
+}
+
+// NewTextRerankFromBytes creates a text reranking model from an ONNX model and
+// tokenizer files held in memory
+func NewTextRerankFromBytes(onnxModel []byte, tokenizer TokenizerBytes, opts ...Option) (*TextRerank, error) {
+	var cErr *C.FastEmbedError
+	var pinner runtime.Pinner
+	defer pinner.Unpin()
+
+	cOnnx := cBytes(&pinner, onnxModel)
+	cTokenizer := tokenizer.toC(&pinner)
+
+	o := newInitOptions(opts)
+	cOpts, freeOpts := o.toC()
+	defer freeOpts()
+
+	handle := C.fastembed_text_rerank_new_from_bytes(&cOnnx, cTokenizer, cOpts, &cErr)
+	if handle == nil {
+		return nil, newError(cErr)
+	}
+
+	tr := &TextRerank{handle: handle, modelDetails: textRerankDetails(handle, o)}
+	runtime.SetFinalizer(tr, func(t *TextRerank) {
+		t.Close()
+	})
+	return tr, nil
+}
+
+// NewTextRerankFromFS creates a text reranking model from files in fsys
+func NewTextRerankFromFS(fsys fs.FS, onnxPath string, tokenizerFiles TokenizerFiles, opts ...Option) (*TextRerank, error) {
+	onnxModel, tokenizer, err := readModelFS(fsys, onnxPath, tokenizerFiles)
+	if err != nil {
+		return nil, err
+	}
+	return NewTextRerankFromBytes(onnxModel, tokenizer, opts...)
+}
@@ -0,0 +1,235 @@
+package fastembed
+
+import (
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// snapshotDir returns the Hugging Face cache snapshot directory of a model
+// that was downloaded into cacheDir
+func snapshotDir(t *testing.T, cacheDir, modelCode string) string {
+	t.Helper()
+
+	repoDir := "models--" + strings.ReplaceAll(modelCode, "/", "--")
+	matches, err := filepath.Glob(filepath.Join(cacheDir, repoDir, "snapshots", "*"))
+	if err != nil || len(matches) == 0 {
+		t.Fatalf("No snapshot found for %s in %s", modelCode, cacheDir)
+	}
+	return matches[0]
+}
+
+// findONNX returns the path of the first ONNX model file below dir, relative to dir
+func findONNX(t *testing.T, dir string) string {
+	t.Helper()
+
+	var found string
+	fs.WalkDir(os.DirFS(dir), ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && found == "" && strings.HasSuffix(path, ".onnx") {
+			found = path
+		}
+		return nil
+	})
+	if found == "" {
+		t.Fatalf("No ONNX model found in %s", dir)
+	}
+	return found
+}
+
+// defaultTokenizerFiles are the tokenizer file names used by Hugging Face model repositories
+var defaultTokenizerFiles = TokenizerFiles{
+	Tokenizer:        "tokenizer.json",
+	Config:           "config.json",
+	SpecialTokensMap: "special_tokens_map.json",
+	TokenizerConfig:  "tokenizer_config.json",
+}
+
+func TestNewTextEmbeddingFromFiles(t *testing.T) {
+	cacheDir := t.TempDir()
+	builtin, err := NewTextEmbedding("Xenova/bge-small-en-v1.5", WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer builtin.Close()
+
+	dir := snapshotDir(t, cacheDir, "Xenova/bge-small-en-v1.5")
+	model, err := NewTextEmbeddingFromFiles(
+		filepath.Join(dir, "onnx", "model.onnx"),
+		TokenizerFiles{
+			Tokenizer:        filepath.Join(dir, "tokenizer.json"),
+			Config:           filepath.Join(dir, "config.json"),
+			SpecialTokensMap: filepath.Join(dir, "special_tokens_map.json"),
+			TokenizerConfig:  filepath.Join(dir, "tokenizer_config.json"),
+		},
+		PoolingCLS,
+	)
+	if err != nil {
+		t.Fatalf("Failed to create text embedding from files: %v", err)
+	}
+	defer model.Close()
+
+	if model.ModelCode() != "" || model.Options().CacheDir != "" {
+		t.Errorf("Expected no model code or cache dir, got %q, %q", model.ModelCode(), model.Options().CacheDir)
+	}
+	if model.Dimension() != builtin.Dimension() {
+		t.Errorf("Expected dimension %d, got %d", builtin.Dimension(), model.Dimension())
+	}
+
+	texts := []string{"Hello, World!", "This is a test."}
+	want, err := builtin.Embed(texts, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed with built-in model: %v", err)
+	}
+	got, err := model.Embed(texts, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed with user-defined model: %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d embeddings, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) {
+			t.Fatalf("Embedding %d: expected dimension %d, got %d", i, len(want[i]), len(got[i]))
+		}
+		for j := range want[i] {
+			if math.Abs(float64(got[i][j]-want[i][j])) > 1e-4 {
+				t.Fatalf("Embedding %d differs from built-in model at %d: %f != %f", i, j, got[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestNewTextEmbeddingFromFiles_MissingFile(t *testing.T) {
+	dir := t.TempDir()
+	model, err := NewTextEmbeddingFromFiles(
+		filepath.Join(dir, "model.onnx"),
+		TokenizerFiles{
+			Tokenizer:        filepath.Join(dir, "tokenizer.json"),
+			Config:           filepath.Join(dir, "config.json"),
+			SpecialTokensMap: filepath.Join(dir, "special_tokens_map.json"),
+			TokenizerConfig:  filepath.Join(dir, "tokenizer_config.json"),
+		},
+		PoolingMean,
+	)
+	if err == nil {
+		model.Close()
+		t.Fatal("Expected an error for missing model files")
+	}
+	if !strings.Contains(err.Error(), "model.onnx") {
+		t.Errorf("Expected error to mention the missing file, got: %v", err)
+	}
+}
+
+func TestReadTokenizerFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"model/tokenizer.json":          {Data: []byte(`{"tokenizer": true}`)},
+		"model/config.json":             {Data: []byte(`{"config": true}`)},
+		"model/special_tokens_map.json": {Data: []byte(`{}`)},
+		"model/tokenizer_config.json":   {Data: []byte(`{"max": 512}`)},
+	}
+
+	files := TokenizerFiles{
+		Tokenizer:        "model/tokenizer.json",
+		Config:           "model/config.json",
+		SpecialTokensMap: "model/special_tokens_map.json",
+		TokenizerConfig:  "model/tokenizer_config.json",
+	}
+	tb, err := ReadTokenizerFiles(fsys, files)
+	if err != nil {
+		t.Fatalf("Failed to read tokenizer files: %v", err)
+	}
+	if string(tb.Tokenizer) != `{"tokenizer": true}` || string(tb.TokenizerConfig) != `{"max": 512}` {
+		t.Errorf("Unexpected tokenizer contents: %+v", tb)
+	}
+
+	files.Config = "model/missing.json"
+	if _, err := ReadTokenizerFiles(fsys, files); err == nil {
+		t.Error("Expected an error for a missing tokenizer file")
+	}
+}
+
+func TestNewTextEmbeddingFromFS(t *testing.T) {
+	cacheDir := t.TempDir()
+	builtin, err := NewTextEmbedding("Xenova/bge-small-en-v1.5", WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer builtin.Close()
+
+	dir := snapshotDir(t, cacheDir, "Xenova/bge-small-en-v1.5")
+	model, err := NewTextEmbeddingFromFS(os.DirFS(dir), findONNX(t, dir), defaultTokenizerFiles, PoolingCLS)
+	if err != nil {
+		t.Fatalf("Failed to create text embedding from fs: %v", err)
+	}
+	defer model.Close()
+
+	embeddings, err := model.Embed([]string{"Hello, World!"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed texts: %v", err)
+	}
+	if len(embeddings) != 1 || len(embeddings[0]) != 384 {
+		t.Errorf("Expected one 384-dimensional embedding, got %d", len(embeddings))
+	}
+}
+
+func TestNewSparseTextEmbeddingFromFS(t *testing.T) {
+	cacheDir := t.TempDir()
+	builtin, err := NewSparseTextEmbedding("Qdrant/Splade_PP_en_v1", WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("Failed to create sparse text embedding: %v", err)
+	}
+	builtin.Close()
+
+	dir := snapshotDir(t, cacheDir, "Qdrant/Splade_PP_en_v1")
+	model, err := NewSparseTextEmbeddingFromFS(os.DirFS(dir), findONNX(t, dir), defaultTokenizerFiles)
+	if err != nil {
+		t.Fatalf("Failed to create sparse text embedding from fs: %v", err)
+	}
+	defer model.Close()
+
+	embeddings, err := model.Embed([]string{"Hello, World!"}, 0)
+	if err != nil {
+		t.Fatalf("Failed to embed texts: %v", err)
+	}
+	if len(embeddings) != 1 || len(embeddings[0].Indices) == 0 {
+		t.Error("Expected one non-empty sparse embedding")
+	}
+}
+
+func TestNewTextRerankFromBytes(t *testing.T) {
+	cacheDir := t.TempDir()
+	builtin, err := NewTextRerank("BAAI/bge-reranker-base", WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("Failed to create text rerank: %v", err)
+	}
+	builtin.Close()
+
+	dir := snapshotDir(t, cacheDir, "BAAI/bge-reranker-base")
+	onnxModel, err := os.ReadFile(filepath.Join(dir, findONNX(t, dir)))
+	if err != nil {
+		t.Fatalf("Failed to read ONNX model: %v", err)
+	}
+	tokenizer, err := ReadTokenizerFiles(os.DirFS(dir), defaultTokenizerFiles)
+	if err != nil {
+		t.Fatalf("Failed to read tokenizer files: %v", err)
+	}
+
+	model, err := NewTextRerankFromBytes(onnxModel, tokenizer)
+	if err != nil {
+		t.Fatalf("Failed to create text rerank from bytes: %v", err)
+	}
+	defer model.Close()
+
+	results, err := model.Rerank("What is a panda?", []string{"Panda is an animal.", "I don't know."}, false, 0)
+	if err != nil {
+		t.Fatalf("Failed to rerank documents: %v", err)
+	}
+	if len(results) != 2 || results[0].Index != 0 {
+		t.Errorf("Expected the panda document to rank first, got %+v", results)
+	}
+}
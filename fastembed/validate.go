@@ -0,0 +1,154 @@
+package fastembed
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// InvalidInput describes an input rejected by validation
+type InvalidInput struct {
+	Index  int    // Position of the input in the call, -1 for a rerank query
+	Reason string // Why the input was rejected
+}
+
+// InputError is returned when inputs of a call fail validation. It lists
+// every invalid input of the call.
+type InputError struct {
+	Inputs []InvalidInput
+}
+
+func (e *InputError) Error() string {
+	first := e.Inputs[0]
+	if len(e.Inputs) == 1 {
+		return fmt.Sprintf("invalid input %d: %s", first.Index, first.Reason)
+	}
+	return fmt.Sprintf("%d invalid inputs, first is input %d: %s", len(e.Inputs), first.Index, first.Reason)
+}
+
+// Is reports whether target is ErrInvalidInput, so that errors.Is matches
+// input errors from validation and from the C library alike
+func (e *InputError) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.code == ErrorInvalidInput
+}
+
+// WithSkipInvalid makes a call skip invalid inputs instead of failing with
+// an *InputError. Skipped inputs get a nil embedding, a zero row in
+// DenseEmbeddings, and are left out of rerank results.
+func WithSkipInvalid(skip bool) CallOption {
+	return func(o *callOptions) {
+		o.skipInvalid = skip
+	}
+}
+
+// checkText returns why a text cannot be embedded, or "" if it can. Texts
+// may be empty and may contain NUL bytes.
+func checkText(text string) string {
+	if !utf8.ValidString(text) {
+		return "invalid UTF-8"
+	}
+	return ""
+}
+
+// checkImagePath returns why an image path cannot be embedded, or "" if it
+// can
+func checkImagePath(path string) string {
+	switch {
+	case path == "":
+		return "empty image path"
+	case strings.IndexByte(path, 0) >= 0:
+		return "image path contains a NUL byte"
+	case !utf8.ValidString(path):
+		return "invalid UTF-8"
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Sprintf("cannot read image: %v", err)
+	}
+	if info.IsDir() {
+		return fmt.Sprintf("image path %s is a directory", path)
+	}
+	return ""
+}
+
+// validateInputs checks every input. If all are valid it returns inputs
+// and nil positions. Otherwise it fails with an *InputError, or, when skip
+// is set, returns the valid inputs along with their positions in inputs.
+func validateInputs(inputs []string, skip bool, check func(string) string) ([]string, []int, error) {
+	var invalid []InvalidInput
+	for i, input := range inputs {
+		if reason := check(input); reason != "" {
+			invalid = append(invalid, InvalidInput{Index: i, Reason: reason})
+		}
+	}
+	if len(invalid) == 0 {
+		return inputs, nil, nil
+	}
+	if !skip {
+		return nil, nil, &InputError{Inputs: invalid}
+	}
+
+	valid := make([]string, 0, len(inputs)-len(invalid))
+	kept := make([]int, 0, len(inputs)-len(invalid))
+	next := 0
+	for i, input := range inputs {
+		if next < len(invalid) && invalid[next].Index == i {
+			next++
+			continue
+		}
+		valid = append(valid, input)
+		kept = append(kept, i)
+	}
+	return valid, kept, nil
+}
+
+// spread places the results of the valid inputs at the positions of their
+// inputs, leaving the zero value for skipped ones. Nil positions mean no
+// input was skipped.
+func spread[R any](results []R, kept []int, n int) []R {
+	if kept == nil {
+		return results
+	}
+	out := make([]R, n)
+	for i, k := range kept {
+		out[k] = results[i]
+	}
+	return out
+}
+
+// dropSkipped sets the rows of skipped inputs to nil. Nil positions mean no
+// input was skipped.
+func dropSkipped(rows [][]float32, kept []int) [][]float32 {
+	if kept == nil {
+		return rows
+	}
+	next := 0
+	for i := range rows {
+		if next < len(kept) && kept[next] == i {
+			next++
+			continue
+		}
+		rows[i] = nil
+	}
+	return rows
+}
+
+// spreadRows moves the first len(kept) rows of data to the rows of their
+// inputs and zeroes the rows of skipped inputs
+func spreadRows(data []float32, dim int, kept []int, n int) {
+	// kept is ascending with kept[i] >= i, so moving rows from the last one
+	// down never overwrites a row that has yet to move
+	next := n - 1
+	for i := len(kept) - 1; i >= 0; i-- {
+		for ; next > kept[i]; next-- {
+			clear(data[next*dim : (next+1)*dim])
+		}
+		copy(data[kept[i]*dim:(kept[i]+1)*dim], data[i*dim:(i+1)*dim])
+		next = kept[i] - 1
+	}
+	for ; next >= 0; next-- {
+		clear(data[next*dim : (next+1)*dim])
+	}
+}
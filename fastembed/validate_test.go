@@ -0,0 +1,131 @@
+package fastembed
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateInputs(t *testing.T) {
+	inputs := []string{"ok", "bad\xff", "fine", "\xc3"}
+
+	valid, kept, err := validateInputs(inputs[:1], false, checkText)
+	if err != nil || kept != nil || len(valid) != 1 {
+		t.Errorf("Expected valid inputs to pass unchanged, got %v, %v, %v", valid, kept, err)
+	}
+
+	_, _, err = validateInputs(inputs, false, checkText)
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Fatalf("Expected an *InputError, got %v", err)
+	}
+	if len(inputErr.Inputs) != 2 || inputErr.Inputs[0].Index != 1 || inputErr.Inputs[1].Index != 3 {
+		t.Errorf("Unexpected invalid inputs: %+v", inputErr.Inputs)
+	}
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected the *InputError to match ErrInvalidInput")
+	}
+
+	valid, kept, err = validateInputs(inputs, true, checkText)
+	if err != nil {
+		t.Fatalf("Expected skipping to succeed, got %v", err)
+	}
+	if len(valid) != 2 || valid[1] != "fine" || len(kept) != 2 || kept[1] != 2 {
+		t.Errorf("Unexpected skip result: %v at %v", valid, kept)
+	}
+
+	spreadOut := spread([]string{"a", "c"}, kept, len(inputs))
+	if len(spreadOut) != 4 || spreadOut[0] != "a" || spreadOut[1] != "" || spreadOut[2] != "c" {
+		t.Errorf("Unexpected spread result: %q", spreadOut)
+	}
+}
+
+func TestSpreadRows(t *testing.T) {
+	// Rows of inputs 1 and 3 of 5, embedded compactly
+	data := []float32{1, 1, 3, 3, 9, 9, 9, 9, 9, 9}
+	spreadRows(data, 2, []int{1, 3}, 5)
+
+	want := []float32{0, 0, 1, 1, 0, 0, 3, 3, 0, 0}
+	for i := range want {
+		if data[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, data)
+		}
+	}
+
+	rows := dropSkipped(DenseEmbeddings{Data: data, Dim: 2}.Rows(), []int{1, 3})
+	if rows[0] != nil || rows[1] == nil || rows[2] != nil || rows[3] == nil || rows[4] != nil {
+		t.Errorf("Expected nil rows for skipped inputs, got %v", rows)
+	}
+}
+
+func TestCheckImagePath(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "image.png")
+	if err := os.WriteFile(image, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, valid := range map[string]bool{
+		image:                             true,
+		"":                                false,
+		dir:                               false,
+		filepath.Join(dir, "missing.png"): false,
+		filepath.Join(dir, "nul\x00.png"): false,
+	} {
+		if got := checkImagePath(path) == ""; got != valid {
+			t.Errorf("checkImagePath(%q) valid = %v, expected %v", path, got, valid)
+		}
+	}
+}
+
+func TestTextEmbedding_InvalidInputs(t *testing.T) {
+	model, err := NewTextEmbedding("")
+	if err != nil {
+		t.Fatalf("Failed to create text embedding: %v", err)
+	}
+	defer model.Close()
+
+	embeddings, err := model.Embed(nil, 0)
+	if err != nil || embeddings == nil || len(embeddings) != 0 {
+		t.Errorf("Expected an empty result for empty input, got %v, %v", embeddings, err)
+	}
+
+	texts := []string{"valid", "in\xffvalid", "also valid"}
+	var inputErr *InputError
+	if _, err := model.Embed(texts, 0); !errors.As(err, &inputErr) || inputErr.Inputs[0].Index != 1 {
+		t.Errorf("Expected an *InputError for input 1, got %v", err)
+	}
+
+	embeddings, err = model.EmbedContext(context.Background(), texts, WithSkipInvalid(true))
+	if err != nil {
+		t.Fatalf("Failed to embed skipping invalid inputs: %v", err)
+	}
+	if len(embeddings) != 3 || embeddings[0] == nil || embeddings[1] != nil || embeddings[2] == nil {
+		t.Errorf("Expected a nil embedding for the skipped input only")
+	}
+}
+
+func TestTextRerank_InvalidInputs(t *testing.T) {
+	model, err := NewTextRerank("")
+	if err != nil {
+		t.Fatalf("Failed to create reranker: %v", err)
+	}
+	defer model.Close()
+
+	results, err := model.Rerank("query", []string{}, false, 0)
+	if err != nil || results == nil || len(results) != 0 {
+		t.Errorf("Expected an empty result for no documents, got %v, %v", results, err)
+	}
+
+	docs := []string{"bad\xff", "Paris is the capital of France."}
+	results, err = model.RerankContext(context.Background(), "capital of France", docs,
+		WithSkipInvalid(true), WithReturnDocuments(true))
+	if err != nil {
+		t.Fatalf("Failed to rerank skipping invalid documents: %v", err)
+	}
+	if len(results) != 1 || results[0].Index != 1 || results[0].Document != docs[1] {
+		t.Errorf("Expected only document 1 in the results, got %+v", results)
+	}
+}
@@ -2,25 +2,108 @@
 #define FASTEMBED_H
 
 #include <stddef.h>
+#include <stdint.h>
 #include <stdbool.h>
 
 #ifdef __cplusplus
 extern "C" {
 #endif
 
-// Opaque handle types
+// Opaque handle types. A handle may be used from several threads at once;
+// calls on the same handle take turns per batch. It must not be freed while
+// a call is using it.
 typedef struct TextEmbeddingHandle TextEmbeddingHandle;
 typedef struct SparseTextEmbeddingHandle SparseTextEmbeddingHandle;
 typedef struct ImageEmbeddingHandle ImageEmbeddingHandle;
 typedef struct TextRerankHandle TextRerankHandle;
+typedef struct LateInteractionTextEmbeddingHandle LateInteractionTextEmbeddingHandle;
 
 // Error handling
+#define FASTEMBED_ERROR_GENERIC 1
+#define FASTEMBED_ERROR_MODEL_NOT_CACHED 2  // Offline mode and model files are missing
+#define FASTEMBED_ERROR_CANCELLED 3         // The call was cancelled through its cancel token
+#define FASTEMBED_ERROR_UNKNOWN_MODEL 4     // The model name does not match a supported model
+#define FASTEMBED_ERROR_DOWNLOAD 5          // Model files could not be downloaded
+#define FASTEMBED_ERROR_INVALID_INPUT 6     // An input or output buffer was rejected
+#define FASTEMBED_ERROR_INFERENCE 7         // The model failed to run on a batch
+#define FASTEMBED_ERROR_PANIC 8             // A panic was caught; the message holds a backtrace summary
+
+// Codes reserved for language bindings, never set by this library
+#define FASTEMBED_ERROR_CLOSED 100          // A model was used after it was closed
+#define FASTEMBED_ERROR_POOL_TIMEOUT 101    // No pooled model became available within the maximum wait
+
 typedef struct {
     char* message;
+    int code;
+    int64_t index;  // Input the error refers to, -1 if none
 } FastEmbedError;
 
 void fastembed_error_free(FastEmbedError* error);
 
+// Cancellation. Embed and rerank calls check the token between batches and
+// fail with FASTEMBED_ERROR_CANCELLED once it is cancelled. A NULL token
+// never cancels. Cancelling is safe from any thread while a call runs.
+typedef struct FastEmbedCancelToken FastEmbedCancelToken;
+
+FastEmbedCancelToken* fastembed_cancel_token_new(void);
+void fastembed_cancel_token_cancel(const FastEmbedCancelToken* token);
+void fastembed_cancel_token_free(FastEmbedCancelToken* token);
+
+// Model a handle was created with. The strings are owned by the handle and
+// stay valid until it is freed.
+typedef struct {
+    const char* model_code;  // Resolved model code, "" for user-defined models
+    const char* cache_dir;   // Model cache directory, "" for user-defined models
+    size_t dim;              // Output dimension, 0 if unknown or not applicable
+    size_t max_length;       // Max input sequence length, 0 for image models
+    const char* query_prefix;    // Recommended prefix for queries, "" if none
+    const char* passage_prefix;  // Recommended prefix for passages, "" if none
+} FastEmbedModelDetails;
+
+// Download progress callback, called with the bytes downloaded so far and the
+// total size of one model file. Files that are already cached are reported
+// once with done == total.
+typedef void (*FastEmbedProgressCallback)(
+    uintptr_t user_data,
+    const char* model_code,
+    const char* file,
+    uint64_t done,
+    uint64_t total
+);
+
+// Model initialization options. Zero/NULL fields keep the fastembed-rs
+// defaults; a NULL options pointer uses the defaults for everything.
+typedef struct {
+    const char* cache_dir;          // Model cache directory
+    size_t max_length;              // Max input sequence length (ignored for image models)
+    int show_download_progress;     // -1 default, 0 hide, 1 show
+    bool offline;                   // Load only from the cache, never download
+    const char* endpoint;           // Model download base URL (Hugging Face mirror)
+    const char* token;              // Bearer token for model downloads
+    FastEmbedProgressCallback progress;  // Optional download progress callback
+    uintptr_t progress_user_data;        // Passed to progress as user_data
+} FastEmbedInitOptions;
+
+// Borrowed in-memory buffer, e.g. a model file embedded in the binary.
+// The data is copied before the call returns.
+typedef struct {
+    const uint8_t* data;
+    size_t len;
+} FastEmbedBytes;
+
+// In-memory Hugging Face tokenizer files of a user-defined model
+typedef struct {
+    FastEmbedBytes tokenizer;           // tokenizer.json
+    FastEmbedBytes config;              // config.json
+    FastEmbedBytes special_tokens_map;  // special_tokens_map.json
+    FastEmbedBytes tokenizer_config;    // tokenizer_config.json
+} FastEmbedTokenizerBytes;
+
+// Input strings are passed packed into one buffer that need not be
+// NUL-terminated: string i is made of the bytes offsets[i]..offsets[i + 1],
+// so offsets has count + 1 entries and strings may contain NUL bytes. The
+// buffer may be NULL when count is 0.
+
 // Result types
 typedef struct {
     float* data;
@@ -46,7 +129,7 @@ typedef struct {
 typedef struct {
     size_t index;
     float score;
-    char* document;
+    char* document;  // NULL unless requested, or if the document contains NUL bytes
 } RerankResultC;
 
 typedef struct {
@@ -57,74 +140,272 @@ typedef struct {
 // Text Embedding API
 TextEmbeddingHandle* fastembed_text_embedding_new(
     const char* model_name,
+    const FastEmbedInitOptions* options,
     FastEmbedError** error
 );
 
 FloatArrayVec* fastembed_text_embedding_embed(
     TextEmbeddingHandle* handle,
-    const char** texts,
+    const char* texts,
+    const size_t* text_offsets,
     size_t num_texts,
     size_t batch_size,
+    const FastEmbedCancelToken* cancel,
+    FastEmbedError** error
+);
+
+// Embeds texts straight into out, a caller-provided buffer of out_len
+// floats, one embedding after another with a stride of the model dimension
+// (FastEmbedModelDetails.dim). Returns false and sets error on failure,
+// including a buffer smaller than num_texts * dim. If truncated is not NULL,
+// it receives num_texts flags marking the texts cut to the max length.
+bool fastembed_text_embedding_embed_into(
+    const TextEmbeddingHandle* handle,
+    const char* texts,
+    const size_t* text_offsets,
+    size_t num_texts,
+    size_t batch_size,
+    float* out,
+    size_t out_len,
+    bool* truncated,
+    const FastEmbedCancelToken* cancel,
     FastEmbedError** error
 );
 
 void fastembed_text_embedding_free(TextEmbeddingHandle* handle);
+void fastembed_text_embedding_details(const TextEmbeddingHandle* handle, FastEmbedModelDetails* details);
+
+// User-defined text embedding models
+#define FASTEMBED_POOLING_DEFAULT 0
+#define FASTEMBED_POOLING_CLS 1
+#define FASTEMBED_POOLING_MEAN 2
+
+typedef struct {
+    const char* tokenizer;           // tokenizer.json
+    const char* config;              // config.json
+    const char* special_tokens_map;  // special_tokens_map.json
+    const char* tokenizer_config;    // tokenizer_config.json
+} FastEmbedTokenizerPaths;
+
+TextEmbeddingHandle* fastembed_text_embedding_new_from_files(
+    const char* onnx_path,
+    const FastEmbedTokenizerPaths* tokenizer_files,
+    int pooling,
+    const FastEmbedInitOptions* options,
+    FastEmbedError** error
+);
+
+TextEmbeddingHandle* fastembed_text_embedding_new_from_bytes(
+    const FastEmbedBytes* onnx,
+    const FastEmbedTokenizerBytes* tokenizer_files,
+    int pooling,
+    const FastEmbedInitOptions* options,
+    FastEmbedError** error
+);
 
 // Sparse Text Embedding API
 SparseTextEmbeddingHandle* fastembed_sparse_text_embedding_new(
     const char* model_name,
+    const FastEmbedInitOptions* options,
+    FastEmbedError** error
+);
+
+SparseTextEmbeddingHandle* fastembed_sparse_text_embedding_new_from_bytes(
+    const FastEmbedBytes* onnx,
+    const FastEmbedTokenizerBytes* tokenizer_files,
+    const FastEmbedInitOptions* options,
     FastEmbedError** error
 );
 
 SparseEmbeddingVec* fastembed_sparse_text_embedding_embed(
     SparseTextEmbeddingHandle* handle,
-    const char** texts,
+    const char* texts,
+    const size_t* text_offsets,
     size_t num_texts,
     size_t batch_size,
+    bool* truncated,  // Optional, like in fastembed_text_embedding_embed_into
+    const FastEmbedCancelToken* cancel,
     FastEmbedError** error
 );
 
 void fastembed_sparse_text_embedding_free(SparseTextEmbeddingHandle* handle);
+void fastembed_sparse_text_embedding_details(const SparseTextEmbeddingHandle* handle, FastEmbedModelDetails* details);
 
 // Image Embedding API
 ImageEmbeddingHandle* fastembed_image_embedding_new(
     const char* model_name,
+    const FastEmbedInitOptions* options,
     FastEmbedError** error
 );
 
 FloatArrayVec* fastembed_image_embedding_embed(
     ImageEmbeddingHandle* handle,
-    const char** image_paths,
+    const char* image_paths,
+    const size_t* path_offsets,
     size_t num_images,
     size_t batch_size,
+    const FastEmbedCancelToken* cancel,
+    FastEmbedError** error
+);
+
+// Like fastembed_text_embedding_embed_into, for images, which are never
+// truncated
+bool fastembed_image_embedding_embed_into(
+    const ImageEmbeddingHandle* handle,
+    const char* image_paths,
+    const size_t* path_offsets,
+    size_t num_images,
+    size_t batch_size,
+    float* out,
+    size_t out_len,
+    const FastEmbedCancelToken* cancel,
     FastEmbedError** error
 );
 
 void fastembed_image_embedding_free(ImageEmbeddingHandle* handle);
+void fastembed_image_embedding_details(const ImageEmbeddingHandle* handle, FastEmbedModelDetails* details);
 
 // Text Reranking API
 TextRerankHandle* fastembed_text_rerank_new(
     const char* model_name,
+    const FastEmbedInitOptions* options,
+    FastEmbedError** error
+);
+
+TextRerankHandle* fastembed_text_rerank_new_from_bytes(
+    const FastEmbedBytes* onnx,
+    const FastEmbedTokenizerBytes* tokenizer_files,
+    const FastEmbedInitOptions* options,
     FastEmbedError** error
 );
 
 RerankResultVec* fastembed_text_rerank_rerank(
     TextRerankHandle* handle,
     const char* query,
-    const char** documents,
+    size_t query_len,
+    const char* documents,
+    const size_t* document_offsets,
     size_t num_documents,
     bool return_documents,
     size_t batch_size,
+    bool* truncated,  // Optional, num_documents flags for query-document pairs cut to the max length
+    const FastEmbedCancelToken* cancel,
     FastEmbedError** error
 );
 
 void fastembed_text_rerank_free(TextRerankHandle* handle);
+void fastembed_text_rerank_details(const TextRerankHandle* handle, FastEmbedModelDetails* details);
+
+// Late-interaction (ColBERT) text embedding API. Models are loaded from a
+// user-defined ONNX model whose tokenizer has the BERT [unused0], [unused1]
+// and [MASK] tokens. Embedding returns one array per text holding the
+// L2-normalized vectors of its tokens, row after row with a stride of the
+// model dimension (FastEmbedModelDetails.dim).
+LateInteractionTextEmbeddingHandle* fastembed_late_interaction_text_embedding_new_from_files(
+    const char* onnx_path,
+    const FastEmbedTokenizerPaths* tokenizer_files,
+    const FastEmbedInitOptions* options,
+    FastEmbedError** error
+);
+
+LateInteractionTextEmbeddingHandle* fastembed_late_interaction_text_embedding_new_from_bytes(
+    const FastEmbedBytes* onnx,
+    const FastEmbedTokenizerBytes* tokenizer_files,
+    const FastEmbedInitOptions* options,
+    FastEmbedError** error
+);
+
+FloatArrayVec* fastembed_late_interaction_text_embedding_embed(
+    const LateInteractionTextEmbeddingHandle* handle,
+    const char* texts,
+    const size_t* text_offsets,
+    size_t num_texts,
+    bool is_query,  // Embed queries rather than documents
+    size_t batch_size,
+    const FastEmbedCancelToken* cancel,
+    FastEmbedError** error
+);
+
+void fastembed_late_interaction_text_embedding_free(LateInteractionTextEmbeddingHandle* handle);
+void fastembed_late_interaction_text_embedding_details(
+    const LateInteractionTextEmbeddingHandle* handle,
+    FastEmbedModelDetails* details
+);
+
+// Tokenizer access. Texts are tokenized with special tokens but without the
+// truncation to the max length that embedding applies.
+typedef struct {
+    uint32_t* ids;
+    size_t* offsets;        // Byte range of token i in the text: offsets[2 * i]..offsets[2 * i + 1]
+    char* tokens;           // Token strings, packed like the inputs
+    size_t* token_offsets;  // Token i is tokens[token_offsets[i]..token_offsets[i + 1]]
+    size_t len;
+} FastEmbedTokens;
+
+FastEmbedTokens* fastembed_text_embedding_tokenize(
+    const TextEmbeddingHandle* handle,
+    const char* text,
+    size_t text_len,
+    FastEmbedError** error
+);
+
+// Writes the token count of each text to counts, which holds num_texts entries
+bool fastembed_text_embedding_count_tokens(
+    const TextEmbeddingHandle* handle,
+    const char* texts,
+    const size_t* text_offsets,
+    size_t num_texts,
+    size_t* counts,
+    FastEmbedError** error
+);
+
+FastEmbedTokens* fastembed_sparse_text_embedding_tokenize(
+    const SparseTextEmbeddingHandle* handle,
+    const char* text,
+    size_t text_len,
+    FastEmbedError** error
+);
+
+bool fastembed_sparse_text_embedding_count_tokens(
+    const SparseTextEmbeddingHandle* handle,
+    const char* texts,
+    const size_t* text_offsets,
+    size_t num_texts,
+    size_t* counts,
+    FastEmbedError** error
+);
+
+FastEmbedTokens* fastembed_text_rerank_tokenize(
+    const TextRerankHandle* handle,
+    const char* text,
+    size_t text_len,
+    FastEmbedError** error
+);
+
+bool fastembed_text_rerank_count_tokens(
+    const TextRerankHandle* handle,
+    const char* texts,
+    const size_t* text_offsets,
+    size_t num_texts,
+    size_t* counts,
+    FastEmbedError** error
+);
+
+void fastembed_tokens_free(FastEmbedTokens* tokens);
 
 // Model Information
 typedef struct {
     char* model_code;
     char* description;
-    size_t dim;
+    size_t dim;                 // Embedding dimension, 0 for rerankers
+    int pooling;                // FASTEMBED_POOLING_*, DEFAULT when not applicable
+    size_t max_tokens;          // Max input tokens the model supports, 0 if unknown
+    char* query_prefix;         // Recommended prefix for queries, "" if none
+    char* passage_prefix;       // Recommended prefix for passages, "" if none
+    char* model_file;           // ONNX file within the model repository
+    uint64_t approx_size;       // Approximate ONNX download size in bytes, 0 if unknown
+    bool quantized;             // Quantized variant of a model
+    bool multilingual;          // Trained on more than one or two languages
 } ModelInfoC;
 
 typedef struct {
@@ -132,13 +413,32 @@ typedef struct {
     size_t len;
 } ModelInfoVec;
 
-// Model listing functions
-ModelInfoVec* fastembed_text_embedding_list_supported_models(void);
-ModelInfoVec* fastembed_sparse_text_embedding_list_supported_models(void);
-ModelInfoVec* fastembed_image_embedding_list_supported_models(void);
-ModelInfoVec* fastembed_text_rerank_list_supported_models(void);
+// Model listing functions. max_tokens and approx_size are read from the
+// files of models cached in cache_dir, or the default cache dir if NULL.
+ModelInfoVec* fastembed_text_embedding_list_supported_models(const char* cache_dir);
+ModelInfoVec* fastembed_sparse_text_embedding_list_supported_models(const char* cache_dir);
+ModelInfoVec* fastembed_image_embedding_list_supported_models(const char* cache_dir);
+ModelInfoVec* fastembed_text_rerank_list_supported_models(const char* cache_dir);
+
+// Model cache
+#define FASTEMBED_MODEL_KIND_TEXT_EMBEDDING 1
+#define FASTEMBED_MODEL_KIND_SPARSE_TEXT_EMBEDDING 2
+#define FASTEMBED_MODEL_KIND_IMAGE_EMBEDDING 3
+#define FASTEMBED_MODEL_KIND_TEXT_RERANK 4
+
+char* fastembed_default_cache_dir(void);
+
+// Downloads all files of a model into the cache without loading it.
+// Returns false and sets error on failure.
+bool fastembed_prefetch(
+    int kind,
+    const char* model_name,
+    const FastEmbedInitOptions* options,
+    FastEmbedError** error
+);
 
 // Memory cleanup
+void fastembed_string_free(char* s);
 void fastembed_float_array_vec_free(FloatArrayVec* vec);
 void fastembed_sparse_embedding_vec_free(SparseEmbeddingVec* vec);
 void fastembed_rerank_result_vec_free(RerankResultVec* vec);
@@ -1,44 +1,1283 @@
 use fastembed::{
     EmbeddingModel, ImageEmbedding, ImageEmbeddingModel, ImageInitOptions, InitOptions,
-    RerankInitOptions, RerankerModel, SparseInitOptions, SparseModel, SparseTextEmbedding,
-    TextEmbedding, TextRerank,
+    InitOptionsUserDefined, OnnxSource, OutputKey, Pooling, RerankInitOptions,
+    RerankInitOptionsUserDefined, RerankerModel, SingleBatchOutput, SparseInitOptions, SparseModel,
+    SparseTextEmbedding, TextEmbedding, TextRerank, TokenizerFiles, UserDefinedEmbeddingModel,
+    UserDefinedRerankingModel, UserDefinedSparseModel,
 };
+use hf_hub::api::sync::ApiBuilder;
+use hf_hub::api::Progress;
+use hf_hub::Cache;
+use std::any::Any;
+use std::backtrace::Backtrace;
+use std::cell::{Cell, RefCell};
+use std::collections::HashMap;
 use std::ffi::{CStr, CString};
+use std::fmt::Debug;
 use std::os::raw::c_char;
+use std::panic::{self, AssertUnwindSafe};
+use std::path::{Path, PathBuf};
 use std::ptr;
 use std::slice;
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::sync::{Mutex, MutexGuard, Once, OnceLock, PoisonError};
+
+// Opaque handles for the models. fastembed-rs models need exclusive access
+// to run, so each is behind a mutex that is held for one batch at a time;
+// concurrent calls on the same handle interleave their batches.
+// Text handles also keep an untruncated copy of the tokenizer outside the
+// mutex for the *_tokenize and *_count_tokens functions.
+pub struct TextEmbeddingHandle(Mutex<TextEmbedding>, ModelDetails, TokenizeFn);
+pub struct SparseTextEmbeddingHandle(Mutex<SparseTextEmbedding>, ModelDetails, TokenizeFn);
+pub struct ImageEmbeddingHandle(Mutex<ImageEmbedding>, ModelDetails);
+pub struct TextRerankHandle(Mutex<TextRerank>, ModelDetails, TokenizeFn);
+pub struct LateInteractionTextEmbeddingHandle(Mutex<TextEmbedding>, ModelDetails);
+
+/// Tokenizes one text without truncation or padding.
+type TokenizeFn = Box<dyn Fn(&str) -> Result<TokenizedText, String> + Send + Sync>;
+
+/// Token ids, tokens and byte offsets of one text.
+struct TokenizedText {
+    ids: Vec<u32>,
+    tokens: Vec<String>,
+    offsets: Vec<(usize, usize)>,
+}
+
+/// Builds a TokenizeFn from a copy of a model's tokenizer with truncation and
+/// padding turned off. Handles build it once when they are created, so that
+/// tokenizing neither copies the tokenizer nor waits for the model. It is a
+/// macro so that the tokenizers crate need not be named.
+macro_rules! untruncated_tokenizer {
+    ($tokenizer:expr) => {{
+        let mut tokenizer = $tokenizer.clone();
+        tokenizer.with_padding(None);
+        let truncation = tokenizer.with_truncation(None).map(|_| ()).map_err(|e| e.to_string());
+        Box::new(move |text: &str| -> Result<TokenizedText, String> {
+            truncation.clone()?;
+            let encoding = tokenizer.encode(text, true).map_err(|e| e.to_string())?;
+            Ok(TokenizedText {
+                ids: encoding.get_ids().to_vec(),
+                tokens: encoding.get_tokens().to_vec(),
+                offsets: encoding.get_offsets().to_vec(),
+            })
+        }) as TokenizeFn
+    }};
+}
+
+/// Locks a model, recovering it if a previous call panicked while holding it.
+fn lock_model<T>(model: &Mutex<T>) -> MutexGuard<'_, T> {
+    model.lock().unwrap_or_else(PoisonError::into_inner)
+}
+
+/// What a handle was created from, reported by the *_details functions.
+struct ModelDetails {
+    model_code: CString,
+    cache_dir: CString,
+    dim: usize,
+    max_length: usize,
+    query_prefix: CString,
+    passage_prefix: CString,
+}
+
+impl ModelDetails {
+    fn new(model_code: &str, cache_dir: &Path, dim: usize, max_length: usize) -> Self {
+        let traits = model_traits(model_code);
+        ModelDetails {
+            model_code: CString::new(model_code).unwrap_or_default(),
+            cache_dir: CString::new(cache_dir.to_string_lossy().into_owned()).unwrap_or_default(),
+            dim,
+            max_length,
+            query_prefix: CString::new(traits.map_or("", |t| t.query_prefix)).unwrap_or_default(),
+            passage_prefix: CString::new(traits.map_or("", |t| t.passage_prefix)).unwrap_or_default(),
+        }
+    }
+
+    /// Details of a model loaded from files or memory, which has no model
+    /// code and is not cached.
+    fn user_defined(dim: usize, max_length: usize) -> Self {
+        ModelDetails {
+            model_code: CString::default(),
+            cache_dir: CString::default(),
+            dim,
+            max_length,
+            query_prefix: CString::default(),
+            passage_prefix: CString::default(),
+        }
+    }
 
-// Opaque handles for the models
-pub struct TextEmbeddingHandle(Box<TextEmbedding>);
-pub struct SparseTextEmbeddingHandle(Box<SparseTextEmbedding>);
-pub struct ImageEmbeddingHandle(Box<ImageEmbedding>);
-pub struct TextRerankHandle(Box<TextRerank>);
+    fn write_to(&self, details: *mut FastEmbedModelDetails) {
+        if details.is_null() {
+            return;
+        }
+        unsafe {
+            *details = FastEmbedModelDetails {
+                model_code: self.model_code.as_ptr(),
+                cache_dir: self.cache_dir.as_ptr(),
+                dim: self.dim,
+                max_length: self.max_length,
+                query_prefix: self.query_prefix.as_ptr(),
+                passage_prefix: self.passage_prefix.as_ptr(),
+            };
+        }
+    }
+}
+
+/// Borrowed view of a handle's ModelDetails, valid until the handle is freed.
+#[repr(C)]
+pub struct FastEmbedModelDetails {
+    pub model_code: *const c_char,
+    pub cache_dir: *const c_char,
+    pub dim: usize,
+    pub max_length: usize,
+    pub query_prefix: *const c_char,
+    pub passage_prefix: *const c_char,
+}
 
 // Error handling
+pub const FASTEMBED_ERROR_GENERIC: i32 = 1;
+pub const FASTEMBED_ERROR_MODEL_NOT_CACHED: i32 = 2;
+pub const FASTEMBED_ERROR_CANCELLED: i32 = 3;
+pub const FASTEMBED_ERROR_UNKNOWN_MODEL: i32 = 4;
+pub const FASTEMBED_ERROR_DOWNLOAD: i32 = 5;
+pub const FASTEMBED_ERROR_INVALID_INPUT: i32 = 6;
+pub const FASTEMBED_ERROR_INFERENCE: i32 = 7;
+pub const FASTEMBED_ERROR_PANIC: i32 = 8;
+
 #[repr(C)]
 pub struct FastEmbedError {
     pub message: *mut c_char,
+    pub code: i32,
+    pub index: i64,
 }
 
 impl FastEmbedError {
     fn from_string(s: String) -> *mut FastEmbedError {
+        FastEmbedError::with_code(FASTEMBED_ERROR_GENERIC, s)
+    }
+
+    fn with_code(code: i32, s: String) -> *mut FastEmbedError {
+        FastEmbedError::with_index(code, s, None)
+    }
+
+    fn with_index(code: i32, s: String, index: Option<usize>) -> *mut FastEmbedError {
         let c_str = CString::new(s).unwrap_or_else(|_| CString::new("Invalid error message").unwrap());
         Box::into_raw(Box::new(FastEmbedError {
             message: c_str.into_raw(),
+            code,
+            index: index.map_or(-1, |i| i as i64),
         }))
     }
 }
 
-#[no_mangle]
-pub extern "C" fn fastembed_error_free(error: *mut FastEmbedError) {
-    if !error.is_null() {
+/// Error code for a failed model constructor: downloads fail with hf-hub
+/// errors somewhere in the chain, everything else is a loading problem.
+fn load_error_code(e: &anyhow::Error) -> i32 {
+    if e.chain().any(|c| c.is::<hf_hub::api::sync::ApiError>()) {
+        FASTEMBED_ERROR_DOWNLOAD
+    } else {
+        FASTEMBED_ERROR_GENERIC
+    }
+}
+
+/// An input that cannot be passed to the model, with its index when it is
+/// one of several strings.
+struct InvalidInput {
+    index: Option<usize>,
+    message: String,
+}
+
+impl InvalidInput {
+    fn new(message: String) -> Self {
+        InvalidInput { index: None, message }
+    }
+
+    fn into_error(self) -> *mut FastEmbedError {
+        FastEmbedError::with_index(FASTEMBED_ERROR_INVALID_INPUT, self.message, self.index)
+    }
+}
+
+// Panics
+
+/// Frames of a panic backtrace kept in the error message.
+const PANIC_BACKTRACE_FRAMES: usize = 8;
+
+thread_local! {
+    /// Location and backtrace of the last panic on this thread, recorded by
+    /// the panic hook for `guard` to report.
+    static LAST_PANIC: RefCell<Option<String>> = RefCell::new(None);
+
+    /// Number of `guard` calls running on this thread. Panics outside them
+    /// are not ours to record and go to the previously installed hook.
+    static GUARD_DEPTH: Cell<usize> = Cell::new(0);
+}
+
+static PANIC_HOOK: Once = Once::new();
+
+/// Runs the body of an exported function. A panic must not unwind into the
+/// caller, so it is caught and reported as a FASTEMBED_ERROR_PANIC error in
+/// `error`, if given, and `on_panic` is returned.
+fn guard<T>(function: &str, error: *mut *mut FastEmbedError, on_panic: T, body: impl FnOnce() -> T) -> T {
+    PANIC_HOOK.call_once(|| {
+        // Keeps panics inside guard from being printed, and hands all others
+        // to the hook that was installed before, e.g. by the host process
+        let previous = panic::take_hook();
+        panic::set_hook(Box::new(move |info| {
+            if GUARD_DEPTH.try_with(Cell::get).unwrap_or(0) == 0 {
+                previous(info);
+                return;
+            }
+            let location = info
+                .location()
+                .map_or_else(|| "unknown location".to_string(), |l| l.to_string());
+            let summary = format!("at {}\n{}", location, backtrace_summary(&Backtrace::force_capture()));
+            LAST_PANIC.with(|last| *last.borrow_mut() = Some(summary));
+        }));
+    });
+
+    // A summary left by a panic that other code caught must not be reported
+    // for a panic re-raised here from a worker thread
+    LAST_PANIC.with(|last| last.borrow_mut().take());
+    GUARD_DEPTH.with(|depth| depth.set(depth.get() + 1));
+    let result = panic::catch_unwind(AssertUnwindSafe(body));
+    GUARD_DEPTH.with(|depth| depth.set(depth.get() - 1));
+
+    match result {
+        Ok(result) => result,
+        Err(payload) => {
+            // Panics re-raised from worker threads were recorded there
+            let summary = LAST_PANIC
+                .with(|last| last.borrow_mut().take())
+                .unwrap_or_else(|| "backtrace unavailable".to_string());
+            if !error.is_null() {
+                unsafe {
+                    *error = FastEmbedError::with_code(
+                        FASTEMBED_ERROR_PANIC,
+                        format!("Panic in {}: {} {}", function, panic_message(&*payload), summary),
+                    );
+                }
+            }
+            on_panic
+        }
+    }
+}
+
+fn panic_message(payload: &(dyn Any + Send)) -> &str {
+    if let Some(s) = payload.downcast_ref::<&str>() {
+        s
+    } else if let Some(s) = payload.downcast_ref::<String>() {
+        s
+    } else {
+        "unknown panic payload"
+    }
+}
+
+/// Names the innermost frames of a backtrace, skipping the frames of the
+/// standard library's panic machinery. Names are only available when the
+/// library is built with symbols.
+fn backtrace_summary(backtrace: &Backtrace) -> String {
+    let rendered = backtrace.to_string();
+    let frames: Vec<&str> = rendered
+        .lines()
+        .filter_map(|line| {
+            let (number, name) = line.trim_start().split_once(": ")?;
+            number.parse::<usize>().ok()?;
+            Some(name)
+        })
+        .filter(|name| {
+            let name = name.trim_start_matches('<');
+            !["std::", "core::", "alloc::", "rust_begin_unwind", "__rust", "fastembed_c::guard"]
+                .iter()
+                .any(|prefix| name.starts_with(prefix))
+        })
+        .take(PANIC_BACKTRACE_FRAMES)
+        .collect();
+    if frames.is_empty() {
+        return "backtrace unavailable".to_string();
+    }
+    format!("backtrace:\n  {}", frames.join("\n  "))
+}
+
+// Initialization options
+#[repr(C)]
+pub struct FastEmbedInitOptions {
+    pub cache_dir: *const c_char,
+    pub max_length: usize,
+    pub show_download_progress: i32,
+    pub offline: bool,
+    pub endpoint: *const c_char,
+    pub token: *const c_char,
+    pub progress: FastEmbedProgressCallback,
+    pub progress_user_data: usize,
+}
+
+pub type FastEmbedProgressCallback =
+    Option<extern "C" fn(usize, *const c_char, *const c_char, u64, u64)>;
+
+/// Forwards download progress to the caller's callback.
+#[derive(Clone, Copy)]
+struct ProgressReporter {
+    callback: extern "C" fn(usize, *const c_char, *const c_char, u64, u64),
+    user_data: usize,
+}
+
+impl ProgressReporter {
+    fn report(&self, model_code: &str, file: &str, done: u64, total: u64) {
+        if let (Ok(code), Ok(file)) = (CString::new(model_code), CString::new(file)) {
+            (self.callback)(self.user_data, code.as_ptr(), file.as_ptr(), done, total);
+        }
+    }
+}
+
+/// Initialization options read from a FastEmbedInitOptions pointer. Unset
+/// fields keep the fastembed-rs defaults.
+#[derive(Default)]
+struct ModelOptions {
+    cache_dir: Option<PathBuf>,
+    max_length: Option<usize>,
+    show_download_progress: Option<bool>,
+    offline: bool,
+    endpoint: Option<String>,
+    token: Option<String>,
+    progress: Option<ProgressReporter>,
+}
+
+/// Reads an optional C string, treating NULL and "" as unset.
+fn optional_string(s: *const c_char, what: &str) -> Result<Option<String>, String> {
+    if s.is_null() {
+        return Ok(None);
+    }
+    match unsafe { CStr::from_ptr(s) }.to_str() {
+        Ok("") => Ok(None),
+        Ok(s) => Ok(Some(s.to_string())),
+        Err(e) => Err(format!("Invalid {}: {}", what, e)),
+    }
+}
+
+impl ModelOptions {
+    fn from_ptr(options: *const FastEmbedInitOptions) -> Result<ModelOptions, String> {
+        if options.is_null() {
+            return Ok(ModelOptions::default());
+        }
+        let options = unsafe { &*options };
+
+        Ok(ModelOptions {
+            cache_dir: optional_string(options.cache_dir, "cache directory")?.map(PathBuf::from),
+            max_length: if options.max_length > 0 {
+                Some(options.max_length)
+            } else {
+                None
+            },
+            show_download_progress: match options.show_download_progress {
+                0 => Some(false),
+                1 => Some(true),
+                _ => None,
+            },
+            offline: options.offline,
+            endpoint: optional_string(options.endpoint, "endpoint")?,
+            token: optional_string(options.token, "auth token")?,
+            progress: options.progress.map(|callback| ProgressReporter {
+                callback,
+                user_data: options.progress_user_data,
+            }),
+        })
+    }
+
+    fn text(&self, model: EmbeddingModel) -> InitOptions {
+        let mut init = InitOptions::new(model);
+        if let Some(dir) = &self.cache_dir {
+            init = init.with_cache_dir(dir.clone());
+        }
+        if let Some(max_length) = self.max_length {
+            init = init.with_max_length(max_length);
+        }
+        if let Some(show) = self.show_download_progress {
+            init = init.with_show_download_progress(show);
+        }
+        init
+    }
+
+    fn sparse(&self, model: SparseModel) -> SparseInitOptions {
+        let mut init = SparseInitOptions::new(model);
+        if let Some(dir) = &self.cache_dir {
+            init = init.with_cache_dir(dir.clone());
+        }
+        if let Some(max_length) = self.max_length {
+            init = init.with_max_length(max_length);
+        }
+        if let Some(show) = self.show_download_progress {
+            init = init.with_show_download_progress(show);
+        }
+        init
+    }
+
+    // Image models have no sequence length, so max_length is not used here.
+    fn image(&self, model: ImageEmbeddingModel) -> ImageInitOptions {
+        let mut init = ImageInitOptions::new(model);
+        if let Some(dir) = &self.cache_dir {
+            init = init.with_cache_dir(dir.clone());
+        }
+        if let Some(show) = self.show_download_progress {
+            init = init.with_show_download_progress(show);
+        }
+        init
+    }
+
+    // User-defined models are loaded from memory, so only max_length applies.
+    fn user_defined(&self) -> InitOptionsUserDefined {
+        let mut init = InitOptionsUserDefined::new();
+        if let Some(max_length) = self.max_length {
+            init = init.with_max_length(max_length);
+        }
+        init
+    }
+
+    fn user_defined_rerank(&self) -> RerankInitOptionsUserDefined {
+        let mut init = RerankInitOptionsUserDefined::new();
+        if let Some(max_length) = self.max_length {
+            init = init.with_max_length(max_length);
+        }
+        init
+    }
+
+    fn rerank(&self, model: RerankerModel) -> RerankInitOptions {
+        let mut init = RerankInitOptions::new(model);
+        if let Some(dir) = &self.cache_dir {
+            init = init.with_cache_dir(dir.clone());
+        }
+        if let Some(max_length) = self.max_length {
+            init = init.with_max_length(max_length);
+        }
+        if let Some(show) = self.show_download_progress {
+            init = init.with_show_download_progress(show);
+        }
+        init
+    }
+}
+
+// User-defined models
+
+// Pooling strategies for user-defined text embedding models
+pub const FASTEMBED_POOLING_DEFAULT: i32 = 0;
+pub const FASTEMBED_POOLING_CLS: i32 = 1;
+pub const FASTEMBED_POOLING_MEAN: i32 = 2;
+
+#[repr(C)]
+pub struct FastEmbedTokenizerPaths {
+    pub tokenizer: *const c_char,
+    pub config: *const c_char,
+    pub special_tokens_map: *const c_char,
+    pub tokenizer_config: *const c_char,
+}
+
+fn pooling_from_c(pooling: i32) -> Result<Option<Pooling>, String> {
+    match pooling {
+        FASTEMBED_POOLING_DEFAULT => Ok(None),
+        FASTEMBED_POOLING_CLS => Ok(Some(Pooling::Cls)),
+        FASTEMBED_POOLING_MEAN => Ok(Some(Pooling::Mean)),
+        other => Err(format!("Invalid pooling type: {}", other)),
+    }
+}
+
+/// Reads a whole file whose path is given as a C string.
+fn read_file(path: *const c_char, what: &str) -> Result<Vec<u8>, String> {
+    if path.is_null() {
+        return Err(format!("Missing {} path", what));
+    }
+    let path = unsafe { CStr::from_ptr(path) }
+        .to_str()
+        .map_err(|e| format!("Invalid UTF-8 in {} path: {}", what, e))?;
+    std::fs::read(path).map_err(|e| format!("Failed to read {} \"{}\": {}", what, path, e))
+}
+
+impl FastEmbedTokenizerPaths {
+    fn read(&self) -> Result<TokenizerFiles, String> {
+        Ok(TokenizerFiles {
+            tokenizer_file: read_file(self.tokenizer, "tokenizer")?,
+            config_file: read_file(self.config, "config")?,
+            special_tokens_map_file: read_file(self.special_tokens_map, "special tokens map")?,
+            tokenizer_config_file: read_file(self.tokenizer_config, "tokenizer config")?,
+        })
+    }
+}
+
+/// A borrowed byte buffer, e.g. a model file that the caller keeps in memory.
+#[repr(C)]
+pub struct FastEmbedBytes {
+    pub data: *const u8,
+    pub len: usize,
+}
+
+impl FastEmbedBytes {
+    fn to_vec(&self, what: &str) -> Result<Vec<u8>, String> {
+        if self.data.is_null() || self.len == 0 {
+            return Err(format!("Missing {} data", what));
+        }
+        Ok(unsafe { slice::from_raw_parts(self.data, self.len) }.to_vec())
+    }
+}
+
+#[repr(C)]
+pub struct FastEmbedTokenizerBytes {
+    pub tokenizer: FastEmbedBytes,
+    pub config: FastEmbedBytes,
+    pub special_tokens_map: FastEmbedBytes,
+    pub tokenizer_config: FastEmbedBytes,
+}
+
+impl FastEmbedTokenizerBytes {
+    fn read(&self) -> Result<TokenizerFiles, String> {
+        Ok(TokenizerFiles {
+            tokenizer_file: self.tokenizer.to_vec("tokenizer")?,
+            config_file: self.config.to_vec("config")?,
+            special_tokens_map_file: self.special_tokens_map.to_vec("special tokens map")?,
+            tokenizer_config_file: self.tokenizer_config.to_vec("tokenizer config")?,
+        })
+    }
+}
+
+fn user_defined_text_model(
+    onnx_file: Vec<u8>,
+    tokenizer_files: TokenizerFiles,
+    pooling: i32,
+) -> Result<UserDefinedEmbeddingModel, String> {
+    let mut model = UserDefinedEmbeddingModel::new(onnx_file, tokenizer_files);
+    if let Some(pooling) = pooling_from_c(pooling)? {
+        model = model.with_pooling(pooling);
+    }
+    Ok(model)
+}
+
+/// Reads the user-defined model files given by path.
+fn read_user_defined_files(
+    onnx_path: *const c_char,
+    tokenizer_files: *const FastEmbedTokenizerPaths,
+) -> Result<(Vec<u8>, TokenizerFiles), String> {
+    if tokenizer_files.is_null() {
+        return Err("Null pointer provided".to_string());
+    }
+    let onnx_file = read_file(onnx_path, "ONNX model")?;
+    let tokenizer_files = unsafe { &*tokenizer_files }.read()?;
+    Ok((onnx_file, tokenizer_files))
+}
+
+/// Copies the user-defined model files given as in-memory buffers.
+fn copy_user_defined_bytes(
+    onnx: *const FastEmbedBytes,
+    tokenizer_files: *const FastEmbedTokenizerBytes,
+) -> Result<(Vec<u8>, TokenizerFiles), String> {
+    if onnx.is_null() || tokenizer_files.is_null() {
+        return Err("Null pointer provided".to_string());
+    }
+    let onnx_file = unsafe { &*onnx }.to_vec("ONNX model")?;
+    let tokenizer_files = unsafe { &*tokenizer_files }.read()?;
+    Ok((onnx_file, tokenizer_files))
+}
+
+/// Creates a text embedding handle from user-defined model files.
+fn new_user_defined_text_embedding(
+    files: Result<(Vec<u8>, TokenizerFiles), String>,
+    pooling: i32,
+    options: *const FastEmbedInitOptions,
+    error: *mut *mut FastEmbedError,
+) -> *mut TextEmbeddingHandle {
+    let options = match ModelOptions::from_ptr(options) {
+        Ok(o) => o,
+        Err(e) => {
+            if !error.is_null() {
+                unsafe {
+                    *error = FastEmbedError::from_string(e);
+                }
+            }
+            return ptr::null_mut();
+        }
+    };
+
+    let model = match files.and_then(|(onnx, tokenizer)| user_defined_text_model(onnx, tokenizer, pooling)) {
+        Ok(m) => m,
+        Err(e) => {
+            if !error.is_null() {
+                unsafe {
+                    *error = FastEmbedError::from_string(e);
+                }
+            }
+            return ptr::null_mut();
+        }
+    };
+
+    let init = options.user_defined();
+    let max_length = init.max_length;
+    match TextEmbedding::try_new_from_user_defined(model, init) {
+        Ok(mut embedding) => {
+            // The output dimension of a user-defined model is only known
+            // once it runs, so probe it with a single short input.
+            let dim = match embedding.embed(vec!["dimension probe"], None) {
+                Ok(embeddings) => embeddings.first().map_or(0, |e| e.len()),
+                Err(e) => {
+                    if !error.is_null() {
+                        unsafe {
+                            *error = FastEmbedError::with_code(
+                                FASTEMBED_ERROR_INFERENCE,
+                                format!("Failed to run text embedding model: {}", e),
+                            );
+                        }
+                    }
+                    return ptr::null_mut();
+                }
+            };
+            let details = ModelDetails::user_defined(dim, max_length);
+            let tokenize = untruncated_tokenizer!(embedding.tokenizer);
+            Box::into_raw(Box::new(TextEmbeddingHandle(Mutex::new(embedding), details, tokenize)))
+        }
+        Err(e) => {
+            if !error.is_null() {
+                unsafe {
+                    *error = FastEmbedError::with_code(
+                        load_error_code(&e),
+                        format!("Failed to create text embedding: {}", e),
+                    );
+                }
+            }
+            ptr::null_mut()
+        }
+    }
+}
+
+// Model cache
+
+/// Marker file touched in a model's cache directory whenever the model is
+/// loaded; the Go cache management functions use it to prune unused models.
+const LAST_USED_FILE: &str = ".fastembed_last_used";
+
+fn model_repo_dir(cache_dir: &Path, model_code: &str) -> PathBuf {
+    cache_dir.join(format!("models--{}", model_code.replace('/', "--")))
+}
+
+/// Records that a model was loaded. Failures are ignored, usage tracking must
+/// never prevent a model from loading.
+fn mark_model_used(cache_dir: &Path, model_code: &str) {
+    let repo_dir = model_repo_dir(cache_dir, model_code);
+    if repo_dir.is_dir() {
+        let _ = std::fs::File::create(repo_dir.join(LAST_USED_FILE));
+    }
+}
+
+/// Cache directory fastembed-rs uses when no cache dir is configured.
+fn default_cache_dir() -> PathBuf {
+    InitOptions::new(EmbeddingModel::BGESmallENV15).cache_dir
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_default_cache_dir() -> *mut c_char {
+    guard("fastembed_default_cache_dir", ptr::null_mut(), ptr::null_mut(), || {
+        CString::new(default_cache_dir().to_string_lossy().into_owned())
+            .unwrap_or_else(|_| CString::new("").unwrap())
+            .into_raw()
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_string_free(s: *mut c_char) {
+    guard("fastembed_string_free", ptr::null_mut(), (), || {
+        if !s.is_null() {
+            unsafe {
+                let _ = CString::from_raw(s);
+            }
+        }
+    })
+}
+
+// Offline mode
+
+/// Checks that all files of a model are present in the Hugging Face cache
+/// layout used by fastembed-rs (models--{org}--{name}/snapshots/{commit}/...),
+/// so that loading the model does not need the network.
+fn ensure_cached(cache_dir: &Path, model_code: &str, files: &[String]) -> Result<(), String> {
+    let missing = missing_cached_files(cache_dir, model_code, files);
+    if missing.is_empty() {
+        return Ok(());
+    }
+    Err(format!(
+        "Model \"{}\" is not available offline, missing files in {}: {}",
+        model_code,
+        cache_dir.display(),
+        missing.join(", ")
+    ))
+}
+
+/// Snapshot directory of the cached main revision of a model, if any.
+fn cached_snapshot(cache_dir: &Path, model_code: &str) -> Option<PathBuf> {
+    let repo_dir = model_repo_dir(cache_dir, model_code);
+    let commit = std::fs::read_to_string(repo_dir.join("refs").join("main")).ok()?;
+    Some(repo_dir.join("snapshots").join(commit.trim()))
+}
+
+fn missing_cached_files(cache_dir: &Path, model_code: &str, files: &[String]) -> Vec<String> {
+    let snapshot = match cached_snapshot(cache_dir, model_code) {
+        Some(snapshot) => snapshot,
+        None => return files.to_vec(),
+    };
+
+    files
+        .iter()
+        .filter(|f| !snapshot.join(f.as_str()).is_file())
+        .cloned()
+        .collect()
+}
+
+// Model downloads
+
+/// Downloads the model files into the cache when a custom endpoint, auth
+/// token or progress callback is configured. fastembed-rs always downloads
+/// from the default Hugging Face endpoint and only prints its own progress,
+/// but it loads files that are already cached without contacting the
+/// network, so fetching them here first is enough for it to pick them up.
+fn download_with_options(
+    options: &ModelOptions,
+    cache_dir: &Path,
+    model_code: &str,
+    files: &[String],
+) -> Result<(), String> {
+    if options.offline
+        || (options.endpoint.is_none() && options.token.is_none() && options.progress.is_none())
+    {
+        return Ok(());
+    }
+    download_model_files(options, cache_dir, model_code, files)
+}
+
+/// Downloads the given model files into the cache, skipping cached files.
+fn download_model_files(
+    options: &ModelOptions,
+    cache_dir: &Path,
+    model_code: &str,
+    files: &[String],
+) -> Result<(), String> {
+    let mut builder = ApiBuilder::new()
+        .with_cache_dir(cache_dir.to_path_buf())
+        .with_progress(options.show_download_progress.unwrap_or(true));
+    if let Some(endpoint) = &options.endpoint {
+        builder = builder.with_endpoint(endpoint.trim_end_matches('/').to_string());
+    }
+    if let Some(token) = &options.token {
+        builder = builder.with_token(Some(token.clone()));
+    }
+
+    let api = builder
+        .build()
+        .map_err(|e| format!("Failed to set up model download: {}", e))?;
+    let repo = api.model(model_code.to_string());
+    let cache = Cache::new(cache_dir.to_path_buf()).model(model_code.to_string());
+    for file in files {
+        let result = match (options.progress, cache.get(file)) {
+            (Some(reporter), Some(path)) => {
+                let size = std::fs::metadata(&path).map(|m| m.len()).unwrap_or(0);
+                reporter.report(model_code, file, size, size);
+                Ok(path)
+            }
+            (Some(reporter), None) => {
+                repo.download_with_progress(file, FileProgress::new(reporter, model_code, file))
+            }
+            (None, _) => repo.get(file),
+        };
+        result.map_err(|e| format!("Failed to download {} of model \"{}\": {}", file, model_code, e))?;
+    }
+    Ok(())
+}
+
+/// hf-hub progress sink for one file.
+struct FileProgress {
+    reporter: ProgressReporter,
+    model_code: String,
+    file: String,
+    done: u64,
+    total: u64,
+}
+
+impl FileProgress {
+    fn new(reporter: ProgressReporter, model_code: &str, file: &str) -> Self {
+        FileProgress {
+            reporter,
+            model_code: model_code.to_string(),
+            file: file.to_string(),
+            done: 0,
+            total: 0,
+        }
+    }
+}
+
+impl Progress for FileProgress {
+    fn init(&mut self, size: usize, _filename: &str) {
+        self.total = size as u64;
+        self.done = 0;
+        self.reporter.report(&self.model_code, &self.file, 0, self.total);
+    }
+
+    fn update(&mut self, size: usize) {
+        self.done += size as u64;
+        self.reporter.report(&self.model_code, &self.file, self.done, self.total);
+    }
+
+    fn finish(&mut self) {}
+}
+
+// Prefetching
+
+pub const FASTEMBED_MODEL_KIND_TEXT_EMBEDDING: i32 = 1;
+pub const FASTEMBED_MODEL_KIND_SPARSE_TEXT_EMBEDDING: i32 = 2;
+pub const FASTEMBED_MODEL_KIND_IMAGE_EMBEDDING: i32 = 3;
+pub const FASTEMBED_MODEL_KIND_TEXT_RERANK: i32 = 4;
+
+/// Resolves a model of the given kind to its code, cache directory and files.
+fn prefetch_target(
+    kind: i32,
+    model_name: Option<&str>,
+    options: &ModelOptions,
+) -> Result<(String, PathBuf, Vec<String>), String> {
+    match kind {
+        FASTEMBED_MODEL_KIND_TEXT_EMBEDDING => {
+            let name = model_name.unwrap_or(DEFAULT_TEXT_MODEL);
+            let c = resolve_model(name, "text embedding", text_candidates())?;
+            Ok((c.code, options.text(c.model).cache_dir, c.files))
+        }
+        FASTEMBED_MODEL_KIND_SPARSE_TEXT_EMBEDDING => {
+            let name = model_name.unwrap_or(DEFAULT_SPARSE_MODEL);
+            let c = resolve_model(name, "sparse text embedding", sparse_candidates())?;
+            Ok((c.code, options.sparse(c.model).cache_dir, c.files))
+        }
+        FASTEMBED_MODEL_KIND_IMAGE_EMBEDDING => {
+            let name = model_name.unwrap_or(DEFAULT_IMAGE_MODEL);
+            let c = resolve_model(name, "image embedding", image_candidates())?;
+            Ok((c.code, options.image(c.model).cache_dir, c.files))
+        }
+        FASTEMBED_MODEL_KIND_TEXT_RERANK => {
+            let name = model_name.unwrap_or(DEFAULT_RERANK_MODEL);
+            let c = resolve_model(name, "rerank", rerank_candidates())?;
+            Ok((c.code, options.rerank(c.model).cache_dir, c.files))
+        }
+        _ => Err(format!("Unknown model kind {}", kind)),
+    }
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_prefetch(
+    kind: i32,
+    model_name: *const c_char,
+    options: *const FastEmbedInitOptions,
+    error: *mut *mut FastEmbedError,
+) -> bool {
+    guard("fastembed_prefetch", error, false, || {
+        let target = ModelOptions::from_ptr(options)
+            .and_then(|options| Ok((optional_string(model_name, "model name")?, options)))
+            .map_err(FastEmbedError::from_string)
+            .and_then(|(name, options)| {
+                prefetch_target(kind, name.as_deref(), &options)
+                    .map(|target| (options, target))
+                    .map_err(|e| FastEmbedError::with_code(FASTEMBED_ERROR_UNKNOWN_MODEL, e))
+            });
+        let (options, (model_code, cache_dir, files)) = match target {
+            Ok(t) => t,
+            Err(e) => {
+                if error.is_null() {
+                    fastembed_error_free(e);
+                } else {
+                    unsafe {
+                        *error = e;
+                    }
+                }
+                return false;
+            }
+        };
+
+        // Offline prefetching only checks that the model is complete; the
+        // download below then just reports the cached files.
+        let result = if options.offline {
+            ensure_cached(&cache_dir, &model_code, &files)
+                .map_err(|e| FastEmbedError::with_code(FASTEMBED_ERROR_MODEL_NOT_CACHED, e))
+        } else {
+            Ok(())
+        }
+        .and_then(|_| {
+            download_model_files(&options, &cache_dir, &model_code, &files)
+                .map_err(|e| FastEmbedError::with_code(FASTEMBED_ERROR_DOWNLOAD, e))
+        });
+
+        match result {
+            Ok(()) => true,
+            Err(e) => {
+                if error.is_null() {
+                    fastembed_error_free(e);
+                } else {
+                    unsafe {
+                        *error = e;
+                    }
+                }
+                false
+            }
+        }
+    })
+}
+
+// Model name resolution
+
+/// Models used when a constructor is called with a NULL model name.
+const DEFAULT_TEXT_MODEL: &str = "BGESmallENV15";
+const DEFAULT_SPARSE_MODEL: &str = "Qdrant/Splade_PP_en_v1";
+const DEFAULT_IMAGE_MODEL: &str = "Qdrant/clip-ViT-B-32-vision";
+const DEFAULT_RERANK_MODEL: &str = "BAAI/bge-reranker-base";
+
+/// Files every tokenizer-based model loads next to its ONNX file.
+const TOKENIZER_FILES: [&str; 4] = [
+    "tokenizer.json",
+    "config.json",
+    "special_tokens_map.json",
+    "tokenizer_config.json",
+];
+
+/// Files image models load next to their ONNX file.
+const IMAGE_PREPROCESSOR_FILES: [&str; 1] = ["preprocessor_config.json"];
+
+/// A supported model together with the files fastembed-rs downloads for it.
+struct ModelCandidate<T> {
+    code: String,
+    model: T,
+    dim: usize,
+    files: Vec<String>,
+}
+
+impl<T> ModelCandidate<T> {
+    fn new(
+        code: String,
+        model: T,
+        dim: usize,
+        model_file: String,
+        additional_files: Vec<String>,
+        extra_files: &[&str],
+    ) -> Self {
+        let mut files = vec![model_file];
+        files.extend(additional_files);
+        files.extend(extra_files.iter().map(|f| f.to_string()));
+        ModelCandidate {
+            code,
+            model,
+            dim,
+            files,
+        }
+    }
+}
+
+fn text_candidates() -> Vec<ModelCandidate<EmbeddingModel>> {
+    TextEmbedding::list_supported_models()
+        .into_iter()
+        .map(|m| {
+            ModelCandidate::new(m.model_code, m.model, m.dim, m.model_file, m.additional_files, &TOKENIZER_FILES)
+        })
+        .collect()
+}
+
+fn sparse_candidates() -> Vec<ModelCandidate<SparseModel>> {
+    SparseTextEmbedding::list_supported_models()
+        .into_iter()
+        .map(|m| {
+            ModelCandidate::new(m.model_code, m.model, m.dim, m.model_file, m.additional_files, &TOKENIZER_FILES)
+        })
+        .collect()
+}
+
+fn image_candidates() -> Vec<ModelCandidate<ImageEmbeddingModel>> {
+    ImageEmbedding::list_supported_models()
+        .into_iter()
+        .map(|m| {
+            ModelCandidate::new(
+                m.model_code,
+                m.model,
+                m.dim,
+                m.model_file,
+                m.additional_files,
+                &IMAGE_PREPROCESSOR_FILES,
+            )
+        })
+        .collect()
+}
+
+// Rerankers output scores, so their dimension is 0.
+fn rerank_candidates() -> Vec<ModelCandidate<RerankerModel>> {
+    TextRerank::list_supported_models()
+        .into_iter()
+        .map(|m| ModelCandidate::new(m.model_code, m.model, 0, m.model_file, m.additional_files, &TOKENIZER_FILES))
+        .collect()
+}
+
+/// Resolves a model name against the supported models of one kind. Both the
+/// Hugging Face model code (e.g. "Xenova/bge-small-en-v1.5") and the
+/// fastembed-rs enum name (e.g. "BGESmallENV15") are accepted, ignoring case.
+fn resolve_model<T: Debug>(
+    name: &str,
+    kind: &str,
+    candidates: Vec<ModelCandidate<T>>,
+) -> Result<ModelCandidate<T>, String> {
+    let position = candidates.iter().position(|c| {
+        c.code.eq_ignore_ascii_case(name) || format!("{:?}", c.model).eq_ignore_ascii_case(name)
+    });
+    if let Some(position) = position {
+        return Ok(candidates.into_iter().nth(position).unwrap());
+    }
+
+    let suggestions = close_matches(name, &candidates);
+    if suggestions.is_empty() {
+        Err(format!("Unknown {} model \"{}\"", kind, name))
+    } else {
+        Err(format!(
+            "Unknown {} model \"{}\", did you mean: {}?",
+            kind,
+            name,
+            suggestions.join(", ")
+        ))
+    }
+}
+
+/// Returns up to three model codes that are similar to the given name.
+fn close_matches<T: Debug>(name: &str, candidates: &[ModelCandidate<T>]) -> Vec<String> {
+    let needle = name.to_lowercase();
+    let mut scored: Vec<(usize, &String)> = candidates
+        .iter()
+        .filter_map(|c| {
+            let code_lower = c.code.to_lowercase();
+            let alias_lower = format!("{:?}", c.model).to_lowercase();
+            let short_name = code_lower.rsplit('/').next().unwrap_or(&code_lower);
+
+            let distance = levenshtein(&needle, &code_lower)
+                .min(levenshtein(&needle, short_name))
+                .min(levenshtein(&needle, &alias_lower));
+            let contains = !needle.is_empty()
+                && (code_lower.contains(&needle) || alias_lower.contains(&needle));
+
+            if contains {
+                Some((0, &c.code))
+            } else if distance <= (needle.len() / 3).max(2) {
+                Some((distance, &c.code))
+            } else {
+                None
+            }
+        })
+        .collect();
+
+    scored.sort_by(|a, b| a.0.cmp(&b.0).then_with(|| a.1.cmp(b.1)));
+    scored.into_iter().take(3).map(|(_, code)| code.clone()).collect()
+}
+
+fn levenshtein(a: &str, b: &str) -> usize {
+    let b_chars: Vec<char> = b.chars().collect();
+    let mut prev: Vec<usize> = (0..=b_chars.len()).collect();
+    let mut curr = vec![0; b_chars.len() + 1];
+
+    for (i, ca) in a.chars().enumerate() {
+        curr[0] = i + 1;
+        for (j, cb) in b_chars.iter().enumerate() {
+            let cost = if ca == *cb { 0 } else { 1 };
+            curr[j + 1] = (prev[j + 1] + 1).min(curr[j] + 1).min(prev[j] + cost);
+        }
+        std::mem::swap(&mut prev, &mut curr);
+    }
+
+    prev[b_chars.len()]
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_error_free(error: *mut FastEmbedError) {
+    guard("fastembed_error_free", ptr::null_mut(), (), || {
+        if !error.is_null() {
+            unsafe {
+                let error = Box::from_raw(error);
+                if !error.message.is_null() {
+                    let _ = CString::from_raw(error.message);
+                }
+            }
+        }
+    })
+}
+
+// Cancellation
+
+/// Cancellation flag shared with the caller. Long-running calls check it
+/// between batches and stop with FASTEMBED_ERROR_CANCELLED once it is set.
+pub struct FastEmbedCancelToken(AtomicBool);
+
+impl FastEmbedCancelToken {
+    fn is_cancelled(&self) -> bool {
+        self.0.load(Ordering::Acquire)
+    }
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_cancel_token_new() -> *mut FastEmbedCancelToken {
+    guard("fastembed_cancel_token_new", ptr::null_mut(), ptr::null_mut(), || {
+        Box::into_raw(Box::new(FastEmbedCancelToken(AtomicBool::new(false))))
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_cancel_token_cancel(token: *const FastEmbedCancelToken) {
+    guard("fastembed_cancel_token_cancel", ptr::null_mut(), (), || {
+        if let Some(token) = unsafe { token.as_ref() } {
+            token.0.store(true, Ordering::Release);
+        }
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_cancel_token_free(token: *mut FastEmbedCancelToken) {
+    guard("fastembed_cancel_token_free", ptr::null_mut(), (), || {
+        if !token.is_null() {
+            unsafe {
+                let _ = Box::from_raw(token);
+            }
+        }
+    })
+}
+
+/// Batch size fastembed-rs uses when none is given.
+const DEFAULT_BATCH_SIZE: usize = 256;
+
+enum CallError {
+    Cancelled,
+    Failed(String),
+}
+
+impl CallError {
+    fn into_error(self, context: &str) -> *mut FastEmbedError {
+        match self {
+            CallError::Cancelled => {
+                FastEmbedError::with_code(FASTEMBED_ERROR_CANCELLED, format!("{}: cancelled", context))
+            }
+            CallError::Failed(e) => {
+                FastEmbedError::with_code(FASTEMBED_ERROR_INFERENCE, format!("{}: {}", context, e))
+            }
+        }
+    }
+}
+
+/// Runs `run` over consecutive batches of `items`, checking the cancel token
+/// before each batch. Results of finished batches are dropped on
+/// cancellation, so nothing is handed to the caller.
+fn run_batches<I: Clone, T, E: std::fmt::Display>(
+    items: &[I],
+    batch_size: usize,
+    cancel: *const FastEmbedCancelToken,
+    mut run: impl FnMut(usize, Vec<I>) -> Result<Vec<T>, E>,
+) -> Result<Vec<T>, CallError> {
+    let cancel = unsafe { cancel.as_ref() };
+    let batch_size = if batch_size > 0 { batch_size } else { DEFAULT_BATCH_SIZE };
+    let mut results = Vec::with_capacity(items.len());
+    for (i, batch) in items.chunks(batch_size).enumerate() {
+        if cancel.map_or(false, |c| c.is_cancelled()) {
+            return Err(CallError::Cancelled);
+        }
+        let batch_results = run(i * batch_size, batch.to_vec()).map_err(|e| CallError::Failed(e.to_string()))?;
+        results.extend(batch_results);
+    }
+    Ok(results)
+}
+
+/// Reads a string of `len` bytes that need not be NUL-terminated, naming
+/// `what` in errors.
+fn read_string(data: *const c_char, len: usize, what: &str) -> Result<String, InvalidInput> {
+    if len == 0 {
+        return Ok(String::new());
+    }
+    if data.is_null() {
+        return Err(InvalidInput::new(format!("Null {} pointer provided", what)));
+    }
+    let bytes = unsafe { slice::from_raw_parts(data as *const u8, len) };
+    std::str::from_utf8(bytes)
+        .map(str::to_string)
+        .map_err(|e| InvalidInput::new(format!("Invalid UTF-8 in {}: {}", what, e)))
+}
+
+/// Reads `len` strings packed into one buffer: string i is made of the bytes
+/// `offsets[i]..offsets[i + 1]` of `data`, so it may contain NUL bytes.
+fn read_packed_strings(
+    data: *const c_char,
+    offsets: *const usize,
+    len: usize,
+    what: &str,
+) -> Result<Vec<String>, InvalidInput> {
+    if len == 0 {
+        return Ok(Vec::new());
+    }
+    if offsets.is_null() {
+        return Err(InvalidInput::new(format!("Null {} offsets provided", what)));
+    }
+    let offsets = unsafe { slice::from_raw_parts(offsets, len + 1) };
+    (0..len)
+        .map(|i| {
+            let (start, end) = (offsets[i], offsets[i + 1]);
+            if start > end {
+                return Err(InvalidInput {
+                    index: Some(i),
+                    message: format!("Invalid offsets for {} {}", what, i),
+                });
+            }
+            let ptr = if data.is_null() { data } else { unsafe { data.add(start) } };
+            read_string(ptr, end - start, &format!("{} {}", what, i)).map_err(|e| InvalidInput {
+                index: Some(i),
+                ..e
+            })
+        })
+        .collect()
+}
+
+/// Checks that a caller-provided buffer holds `rows` embeddings of `dim`
+/// floats and returns it as a slice.
+fn output_rows<'a>(out: *mut f32, out_len: usize, rows: usize, dim: usize) -> Result<&'a mut [f32], InvalidInput> {
+    if dim == 0 {
+        return Err(InvalidInput::new("Embedding dimension of the model is unknown".to_string()));
+    }
+    if rows * dim > out_len {
+        return Err(InvalidInput::new(format!(
+            "Output buffer holds {} floats, {} needed for {} embeddings of dimension {}",
+            out_len,
+            rows * dim,
+            rows,
+            dim
+        )));
+    }
+    if out.is_null() {
+        return Err(InvalidInput::new("Null output buffer provided".to_string()));
+    }
+    Ok(unsafe { slice::from_raw_parts_mut(out, rows * dim) })
+}
+
+/// Copies the embeddings of a batch starting at row `first` into `out`.
+fn copy_rows(out: &mut [f32], first: usize, dim: usize, embeddings: &[Vec<f32>]) -> Result<(), String> {
+    for (i, embedding) in embeddings.iter().enumerate() {
+        if embedding.len() != dim {
+            return Err(format!("Embedding has dimension {}, expected {}", embedding.len(), dim));
+        }
+        let start = (first + i) * dim;
+        out[start..start + dim].copy_from_slice(embedding);
+    }
+    Ok(())
+}
+
+/// Flags the inputs of a batch starting at `first` that the model truncates
+/// to its max length, if the caller passed `out`. The model's tokenizer
+/// truncates, so an input was cut if its encoding overflowed. `overflows` is
+/// lazy, so inputs are only tokenized a second time when flags are wanted.
+fn mark_truncated<E: std::fmt::Display>(
+    out: *mut bool,
+    first: usize,
+    overflows: impl Iterator<Item = Result<bool, E>>,
+) -> Result<(), String> {
+    if out.is_null() {
+        return Ok(());
+    }
+    for (i, overflow) in overflows.enumerate() {
+        let overflow = overflow.map_err(|e| format!("Tokenization failed: {}", e))?;
         unsafe {
-            let error = Box::from_raw(error);
-            if !error.message.is_null() {
-                let _ = CString::from_raw(error.message);
-            }
+            *out.add(first + i) = overflow;
         }
     }
+    Ok(())
 }
 
 // Result types
@@ -80,319 +1319,1257 @@ pub struct RerankResultVec {
     pub len: usize,
 }
 
-// Text Embedding Functions
+// Text Embedding Functions
+#[no_mangle]
+pub extern "C" fn fastembed_text_embedding_new(
+    model_name: *const c_char,
+    options: *const FastEmbedInitOptions,
+    error: *mut *mut FastEmbedError,
+) -> *mut TextEmbeddingHandle {
+    guard("fastembed_text_embedding_new", error, ptr::null_mut(), || {
+        let options = match ModelOptions::from_ptr(options) {
+            Ok(o) => o,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::from_string(e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let model_str = unsafe {
+            if model_name.is_null() {
+                DEFAULT_TEXT_MODEL
+            } else {
+                match CStr::from_ptr(model_name).to_str() {
+                    Ok(s) => s,
+                    Err(e) => {
+                        if !error.is_null() {
+                            *error = FastEmbedError::from_string(format!("Invalid model name: {}", e));
+                        }
+                        return ptr::null_mut();
+                    }
+                }
+            }
+        };
+
+        let candidate = match resolve_model(model_str, "text embedding", text_candidates()) {
+            Ok(c) => c,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(FASTEMBED_ERROR_UNKNOWN_MODEL, e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let init = options.text(candidate.model);
+        if options.offline {
+            if let Err(e) = ensure_cached(&init.cache_dir, &candidate.code, &candidate.files) {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(FASTEMBED_ERROR_MODEL_NOT_CACHED, e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        }
+        if let Err(e) = download_with_options(&options, &init.cache_dir, &candidate.code, &candidate.files) {
+            if !error.is_null() {
+                unsafe {
+                    *error = FastEmbedError::with_code(FASTEMBED_ERROR_DOWNLOAD, e);
+                }
+            }
+            return ptr::null_mut();
+        }
+
+        let cache_dir = init.cache_dir.clone();
+        let details = ModelDetails::new(&candidate.code, &cache_dir, candidate.dim, init.max_length);
+        match TextEmbedding::try_new(init) {
+            Ok(embedding) => {
+                mark_model_used(&cache_dir, &candidate.code);
+                let tokenize = untruncated_tokenizer!(embedding.tokenizer);
+                Box::into_raw(Box::new(TextEmbeddingHandle(Mutex::new(embedding), details, tokenize)))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(
+                            load_error_code(&e),
+                            format!("Failed to create text embedding: {}", e),
+                        );
+                    }
+                }
+                ptr::null_mut()
+            }
+        }
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_text_embedding_new_from_files(
+    onnx_path: *const c_char,
+    tokenizer_files: *const FastEmbedTokenizerPaths,
+    pooling: i32,
+    options: *const FastEmbedInitOptions,
+    error: *mut *mut FastEmbedError,
+) -> *mut TextEmbeddingHandle {
+    guard("fastembed_text_embedding_new_from_files", error, ptr::null_mut(), || {
+        let files = read_user_defined_files(onnx_path, tokenizer_files);
+        new_user_defined_text_embedding(files, pooling, options, error)
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_text_embedding_new_from_bytes(
+    onnx: *const FastEmbedBytes,
+    tokenizer_files: *const FastEmbedTokenizerBytes,
+    pooling: i32,
+    options: *const FastEmbedInitOptions,
+    error: *mut *mut FastEmbedError,
+) -> *mut TextEmbeddingHandle {
+    guard("fastembed_text_embedding_new_from_bytes", error, ptr::null_mut(), || {
+        let files = copy_user_defined_bytes(onnx, tokenizer_files);
+        new_user_defined_text_embedding(files, pooling, options, error)
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_text_embedding_embed(
+    handle: *mut TextEmbeddingHandle,
+    texts: *const c_char,
+    text_offsets: *const usize,
+    num_texts: usize,
+    batch_size: usize,
+    cancel: *const FastEmbedCancelToken,
+    error: *mut *mut FastEmbedError,
+) -> *mut FloatArrayVec {
+    guard("fastembed_text_embedding_embed", error, ptr::null_mut(), || {
+        if handle.is_null() {
+            if !error.is_null() {
+                unsafe {
+                    *error = FastEmbedError::from_string("Null pointer provided".to_string());
+                }
+            }
+            return ptr::null_mut();
+        }
+
+        let handle = unsafe { &*handle };
+        let text_vec = match read_packed_strings(texts, text_offsets, num_texts, "text") {
+            Ok(strings) => strings,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error();
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let embeddings = run_batches(&text_vec, batch_size, cancel, |_, batch| {
+            let n = batch.len();
+            lock_model(&handle.0).embed(batch, Some(n))
+        });
+
+        match embeddings {
+            Ok(embeddings) => {
+                let mut arrays: Vec<FloatArray> = embeddings
+                    .into_iter()
+                    .map(|emb| {
+                        let mut boxed_slice = emb.into_boxed_slice();
+                        let len = boxed_slice.len();
+                        let data = boxed_slice.as_mut_ptr();
+                        std::mem::forget(boxed_slice);
+                        FloatArray { data, len }
+                    })
+                    .collect();
+
+                let len = arrays.len();
+                let arrays_ptr = arrays.as_mut_ptr();
+                std::mem::forget(arrays);
+
+                Box::into_raw(Box::new(FloatArrayVec {
+                    arrays: arrays_ptr,
+                    len,
+                }))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error("Embedding failed");
+                    }
+                }
+                ptr::null_mut()
+            }
+        }
+    })
+}
+
+/// Embeds texts into `out`, a caller-provided buffer of `out_len` floats,
+/// row after row with a stride of the model dimension.
+#[no_mangle]
+pub extern "C" fn fastembed_text_embedding_embed_into(
+    handle: *const TextEmbeddingHandle,
+    texts: *const c_char,
+    text_offsets: *const usize,
+    num_texts: usize,
+    batch_size: usize,
+    out: *mut f32,
+    out_len: usize,
+    truncated: *mut bool,
+    cancel: *const FastEmbedCancelToken,
+    error: *mut *mut FastEmbedError,
+) -> bool {
+    guard("fastembed_text_embedding_embed_into", error, false, || {
+        if handle.is_null() {
+            if !error.is_null() {
+                unsafe {
+                    *error = FastEmbedError::from_string("Null pointer provided".to_string());
+                }
+            }
+            return false;
+        }
+
+        let handle = unsafe { &*handle };
+        let dim = handle.1.dim;
+        let prepared = read_packed_strings(texts, text_offsets, num_texts, "text")
+            .and_then(|texts| output_rows(out, out_len, texts.len(), dim).map(|out| (texts, out)));
+        let (text_vec, out) = match prepared {
+            Ok(prepared) => prepared,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error();
+                    }
+                }
+                return false;
+            }
+        };
+
+        let result = run_batches(&text_vec, batch_size, cancel, |offset, batch| {
+            let n = batch.len();
+            let mut model = lock_model(&handle.0);
+            mark_truncated(
+                truncated,
+                offset,
+                batch.iter().map(|text| {
+                    model
+                        .tokenizer
+                        .encode(text.as_str(), true)
+                        .map(|encoding| !encoding.get_overflowing().is_empty())
+                }),
+            )?;
+            let embeddings = model.embed(batch, Some(n)).map_err(|e| e.to_string())?;
+            copy_rows(out, offset, dim, &embeddings)?;
+            Ok::<Vec<()>, String>(Vec::new())
+        });
+
+        match result {
+            Ok(_) => true,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error("Embedding failed");
+                    }
+                }
+                false
+            }
+        }
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_text_embedding_free(handle: *mut TextEmbeddingHandle) {
+    guard("fastembed_text_embedding_free", ptr::null_mut(), (), || {
+        if !handle.is_null() {
+            unsafe {
+                let _ = Box::from_raw(handle);
+            }
+        }
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_text_embedding_details(
+    handle: *const TextEmbeddingHandle,
+    details: *mut FastEmbedModelDetails,
+) {
+    guard("fastembed_text_embedding_details", ptr::null_mut(), (), || {
+        if let Some(handle) = unsafe { handle.as_ref() } {
+            handle.1.write_to(details);
+        }
+    })
+}
+
+// Sparse Text Embedding Functions
+#[no_mangle]
+pub extern "C" fn fastembed_sparse_text_embedding_new(
+    model_name: *const c_char,
+    options: *const FastEmbedInitOptions,
+    error: *mut *mut FastEmbedError,
+) -> *mut SparseTextEmbeddingHandle {
+    guard("fastembed_sparse_text_embedding_new", error, ptr::null_mut(), || {
+        let options = match ModelOptions::from_ptr(options) {
+            Ok(o) => o,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::from_string(e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let model_str = unsafe {
+            if model_name.is_null() {
+                DEFAULT_SPARSE_MODEL
+            } else {
+                match CStr::from_ptr(model_name).to_str() {
+                    Ok(s) => s,
+                    Err(e) => {
+                        if !error.is_null() {
+                            *error = FastEmbedError::from_string(format!("Invalid model name: {}", e));
+                        }
+                        return ptr::null_mut();
+                    }
+                }
+            }
+        };
+
+        let candidate = match resolve_model(model_str, "sparse text embedding", sparse_candidates()) {
+            Ok(c) => c,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(FASTEMBED_ERROR_UNKNOWN_MODEL, e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let init = options.sparse(candidate.model);
+        if options.offline {
+            if let Err(e) = ensure_cached(&init.cache_dir, &candidate.code, &candidate.files) {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(FASTEMBED_ERROR_MODEL_NOT_CACHED, e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        }
+        if let Err(e) = download_with_options(&options, &init.cache_dir, &candidate.code, &candidate.files) {
+            if !error.is_null() {
+                unsafe {
+                    *error = FastEmbedError::with_code(FASTEMBED_ERROR_DOWNLOAD, e);
+                }
+            }
+            return ptr::null_mut();
+        }
+
+        let cache_dir = init.cache_dir.clone();
+        let details = ModelDetails::new(&candidate.code, &cache_dir, candidate.dim, init.max_length);
+        match SparseTextEmbedding::try_new(init) {
+            Ok(embedding) => {
+                mark_model_used(&cache_dir, &candidate.code);
+                let tokenize = untruncated_tokenizer!(embedding.tokenizer);
+                Box::into_raw(Box::new(SparseTextEmbeddingHandle(Mutex::new(embedding), details, tokenize)))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(
+                            load_error_code(&e),
+                            format!("Failed to create sparse text embedding: {}", e),
+                        );
+                    }
+                }
+                ptr::null_mut()
+            }
+        }
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_sparse_text_embedding_new_from_bytes(
+    onnx: *const FastEmbedBytes,
+    tokenizer_files: *const FastEmbedTokenizerBytes,
+    options: *const FastEmbedInitOptions,
+    error: *mut *mut FastEmbedError,
+) -> *mut SparseTextEmbeddingHandle {
+    guard("fastembed_sparse_text_embedding_new_from_bytes", error, ptr::null_mut(), || {
+        let options = match ModelOptions::from_ptr(options) {
+            Ok(o) => o,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::from_string(e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let model = match copy_user_defined_bytes(onnx, tokenizer_files) {
+            Ok((onnx_file, tokenizer_files)) => UserDefinedSparseModel::new(onnx_file, tokenizer_files),
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::from_string(e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let init = options.user_defined();
+        let details = ModelDetails::user_defined(0, init.max_length);
+        match SparseTextEmbedding::try_new_from_user_defined(model, init) {
+            Ok(embedding) => {
+                let tokenize = untruncated_tokenizer!(embedding.tokenizer);
+                Box::into_raw(Box::new(SparseTextEmbeddingHandle(Mutex::new(embedding), details, tokenize)))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(
+                            load_error_code(&e),
+                            format!("Failed to create sparse text embedding: {}", e),
+                        );
+                    }
+                }
+                ptr::null_mut()
+            }
+        }
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_sparse_text_embedding_embed(
+    handle: *mut SparseTextEmbeddingHandle,
+    texts: *const c_char,
+    text_offsets: *const usize,
+    num_texts: usize,
+    batch_size: usize,
+    truncated: *mut bool,
+    cancel: *const FastEmbedCancelToken,
+    error: *mut *mut FastEmbedError,
+) -> *mut SparseEmbeddingVec {
+    guard("fastembed_sparse_text_embedding_embed", error, ptr::null_mut(), || {
+        if handle.is_null() {
+            if !error.is_null() {
+                unsafe {
+                    *error = FastEmbedError::from_string("Null pointer provided".to_string());
+                }
+            }
+            return ptr::null_mut();
+        }
+
+        let handle = unsafe { &*handle };
+        let text_vec = match read_packed_strings(texts, text_offsets, num_texts, "text") {
+            Ok(strings) => strings,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error();
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let embeddings = run_batches(&text_vec, batch_size, cancel, |offset, batch| {
+            let n = batch.len();
+            let mut model = lock_model(&handle.0);
+            mark_truncated(
+                truncated,
+                offset,
+                batch.iter().map(|text| {
+                    model
+                        .tokenizer
+                        .encode(text.as_str(), true)
+                        .map(|encoding| !encoding.get_overflowing().is_empty())
+                }),
+            )?;
+            model.embed(batch, Some(n)).map_err(|e| e.to_string())
+        });
+
+        match embeddings {
+            Ok(embeddings) => {
+                let mut sparse_embs: Vec<SparseEmbeddingC> = embeddings
+                    .into_iter()
+                    .map(|emb| {
+                        let mut indices_vec = emb.indices;
+                        let mut values_vec = emb.values;
+                        let len = indices_vec.len();
+                        let indices_ptr = indices_vec.as_mut_ptr();
+                        let values_ptr = values_vec.as_mut_ptr();
+                        std::mem::forget(indices_vec);
+                        std::mem::forget(values_vec);
+                        SparseEmbeddingC {
+                            indices: indices_ptr,
+                            values: values_ptr,
+                            len,
+                        }
+                    })
+                    .collect();
+
+                let len = sparse_embs.len();
+                let embeddings_ptr = sparse_embs.as_mut_ptr();
+                std::mem::forget(sparse_embs);
+
+                Box::into_raw(Box::new(SparseEmbeddingVec {
+                    embeddings: embeddings_ptr,
+                    len,
+                }))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error("Sparse embedding failed");
+                    }
+                }
+                ptr::null_mut()
+            }
+        }
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_sparse_text_embedding_free(handle: *mut SparseTextEmbeddingHandle) {
+    guard("fastembed_sparse_text_embedding_free", ptr::null_mut(), (), || {
+        if !handle.is_null() {
+            unsafe {
+                let _ = Box::from_raw(handle);
+            }
+        }
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_sparse_text_embedding_details(
+    handle: *const SparseTextEmbeddingHandle,
+    details: *mut FastEmbedModelDetails,
+) {
+    guard("fastembed_sparse_text_embedding_details", ptr::null_mut(), (), || {
+        if let Some(handle) = unsafe { handle.as_ref() } {
+            handle.1.write_to(details);
+        }
+    })
+}
+
+// Image Embedding Functions
 #[no_mangle]
-pub extern "C" fn fastembed_text_embedding_new(
+pub extern "C" fn fastembed_image_embedding_new(
     model_name: *const c_char,
+    options: *const FastEmbedInitOptions,
     error: *mut *mut FastEmbedError,
-) -> *mut TextEmbeddingHandle {
-    let model_str = unsafe {
-        if model_name.is_null() {
-            "BAAI/bge-small-en-v1.5"
-        } else {
-            match CStr::from_ptr(model_name).to_str() {
-                Ok(s) => s,
-                Err(e) => {
-                    if !error.is_null() {
-                        *error = FastEmbedError::from_string(format!("Invalid model name: {}", e));
+) -> *mut ImageEmbeddingHandle {
+    guard("fastembed_image_embedding_new", error, ptr::null_mut(), || {
+        let options = match ModelOptions::from_ptr(options) {
+            Ok(o) => o,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::from_string(e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let model_str = unsafe {
+            if model_name.is_null() {
+                DEFAULT_IMAGE_MODEL
+            } else {
+                match CStr::from_ptr(model_name).to_str() {
+                    Ok(s) => s,
+                    Err(e) => {
+                        if !error.is_null() {
+                            *error = FastEmbedError::from_string(format!("Invalid model name: {}", e));
+                        }
+                        return ptr::null_mut();
                     }
-                    return ptr::null_mut();
                 }
             }
-        }
-    };
+        };
 
-    let model = match model_str {
-        "AllMiniLML6V2" => EmbeddingModel::AllMiniLML6V2,
-        "BGESmallENV15" => EmbeddingModel::BGESmallENV15,
-        "BGEBaseENV15" => EmbeddingModel::BGEBaseENV15,
-        "BGELargeENV15" => EmbeddingModel::BGELargeENV15,
-        _ => EmbeddingModel::BGESmallENV15, // default
-    };
+        let candidate = match resolve_model(model_str, "image embedding", image_candidates()) {
+            Ok(c) => c,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(FASTEMBED_ERROR_UNKNOWN_MODEL, e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
 
-    match TextEmbedding::try_new(InitOptions::new(model)) {
-        Ok(embedding) => Box::into_raw(Box::new(TextEmbeddingHandle(Box::new(embedding)))),
-        Err(e) => {
+        let init = options.image(candidate.model);
+        if options.offline {
+            if let Err(e) = ensure_cached(&init.cache_dir, &candidate.code, &candidate.files) {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(FASTEMBED_ERROR_MODEL_NOT_CACHED, e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        }
+        if let Err(e) = download_with_options(&options, &init.cache_dir, &candidate.code, &candidate.files) {
             if !error.is_null() {
                 unsafe {
-                    *error = FastEmbedError::from_string(format!("Failed to create text embedding: {}", e));
+                    *error = FastEmbedError::with_code(FASTEMBED_ERROR_DOWNLOAD, e);
                 }
             }
-            ptr::null_mut()
+            return ptr::null_mut();
         }
-    }
+
+        let cache_dir = init.cache_dir.clone();
+        let details = ModelDetails::new(&candidate.code, &cache_dir, candidate.dim, 0);
+        match ImageEmbedding::try_new(init) {
+            Ok(embedding) => {
+                mark_model_used(&cache_dir, &candidate.code);
+                Box::into_raw(Box::new(ImageEmbeddingHandle(Mutex::new(embedding), details)))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(
+                            load_error_code(&e),
+                            format!("Failed to create image embedding: {}", e),
+                        );
+                    }
+                }
+                ptr::null_mut()
+            }
+        }
+    })
 }
 
 #[no_mangle]
-pub extern "C" fn fastembed_text_embedding_embed(
-    handle: *mut TextEmbeddingHandle,
-    texts: *const *const c_char,
-    num_texts: usize,
+pub extern "C" fn fastembed_image_embedding_embed(
+    handle: *mut ImageEmbeddingHandle,
+    image_paths: *const c_char,
+    path_offsets: *const usize,
+    num_images: usize,
     batch_size: usize,
+    cancel: *const FastEmbedCancelToken,
     error: *mut *mut FastEmbedError,
 ) -> *mut FloatArrayVec {
-    if handle.is_null() || texts.is_null() {
-        if !error.is_null() {
-            unsafe {
-                *error = FastEmbedError::from_string("Null pointer provided".to_string());
-            }
-        }
-        return ptr::null_mut();
-    }
-
-    let handle = unsafe { &mut *handle };
-    let text_slice = unsafe { slice::from_raw_parts(texts, num_texts) };
-
-    let mut text_vec = Vec::new();
-    for &text_ptr in text_slice {
-        if text_ptr.is_null() {
+    guard("fastembed_image_embedding_embed", error, ptr::null_mut(), || {
+        if handle.is_null() {
             if !error.is_null() {
                 unsafe {
-                    *error = FastEmbedError::from_string("Null text pointer in array".to_string());
+                    *error = FastEmbedError::from_string("Null pointer provided".to_string());
                 }
             }
             return ptr::null_mut();
         }
-        let text = unsafe { CStr::from_ptr(text_ptr).to_str() };
-        match text {
-            Ok(s) => text_vec.push(s.to_string()),
+
+        let handle = unsafe { &*handle };
+        let path_vec = match read_packed_strings(image_paths, path_offsets, num_images, "path") {
+            Ok(strings) => strings,
             Err(e) => {
                 if !error.is_null() {
                     unsafe {
-                        *error = FastEmbedError::from_string(format!("Invalid UTF-8 in text: {}", e));
+                        *error = e.into_error();
                     }
                 }
                 return ptr::null_mut();
             }
-        }
-    }
-
-    let batch_size_opt = if batch_size > 0 { Some(batch_size) } else { None };
-
-    match handle.0.embed(text_vec, batch_size_opt) {
-        Ok(embeddings) => {
-            let mut arrays: Vec<FloatArray> = embeddings
-                .into_iter()
-                .map(|emb| {
-                    let mut boxed_slice = emb.into_boxed_slice();
-                    let len = boxed_slice.len();
-                    let data = boxed_slice.as_mut_ptr();
-                    std::mem::forget(boxed_slice);
-                    FloatArray { data, len }
-                })
-                .collect();
+        };
 
-            let len = arrays.len();
-            let arrays_ptr = arrays.as_mut_ptr();
-            std::mem::forget(arrays);
+        let embeddings = run_batches(&path_vec, batch_size, cancel, |_, batch| {
+            let n = batch.len();
+            lock_model(&handle.0).embed(batch, Some(n))
+        });
 
-            Box::into_raw(Box::new(FloatArrayVec {
-                arrays: arrays_ptr,
-                len,
-            }))
+        match embeddings {
+            Ok(embeddings) => {
+                let mut arrays: Vec<FloatArray> = embeddings
+                    .into_iter()
+                    .map(|emb| {
+                        let mut boxed_slice = emb.into_boxed_slice();
+                        let len = boxed_slice.len();
+                        let data = boxed_slice.as_mut_ptr();
+                        std::mem::forget(boxed_slice);
+                        FloatArray { data, len }
+                    })
+                    .collect();
+
+                let len = arrays.len();
+                let arrays_ptr = arrays.as_mut_ptr();
+                std::mem::forget(arrays);
+
+                Box::into_raw(Box::new(FloatArrayVec {
+                    arrays: arrays_ptr,
+                    len,
+                }))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error("Image embedding failed");
+                    }
+                }
+                ptr::null_mut()
+            }
         }
-        Err(e) => {
+    })
+}
+
+/// Embeds images into `out`, a caller-provided buffer of `out_len` floats,
+/// row after row with a stride of the model dimension.
+#[no_mangle]
+pub extern "C" fn fastembed_image_embedding_embed_into(
+    handle: *const ImageEmbeddingHandle,
+    image_paths: *const c_char,
+    path_offsets: *const usize,
+    num_images: usize,
+    batch_size: usize,
+    out: *mut f32,
+    out_len: usize,
+    cancel: *const FastEmbedCancelToken,
+    error: *mut *mut FastEmbedError,
+) -> bool {
+    guard("fastembed_image_embedding_embed_into", error, false, || {
+        if handle.is_null() {
             if !error.is_null() {
                 unsafe {
-                    *error = FastEmbedError::from_string(format!("Embedding failed: {}", e));
+                    *error = FastEmbedError::from_string("Null pointer provided".to_string());
                 }
             }
-            ptr::null_mut()
+            return false;
         }
-    }
+
+        let handle = unsafe { &*handle };
+        let dim = handle.1.dim;
+        let prepared = read_packed_strings(image_paths, path_offsets, num_images, "path")
+            .and_then(|paths| output_rows(out, out_len, paths.len(), dim).map(|out| (paths, out)));
+        let (path_vec, out) = match prepared {
+            Ok(prepared) => prepared,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error();
+                    }
+                }
+                return false;
+            }
+        };
+
+        let result = run_batches(&path_vec, batch_size, cancel, |offset, batch| {
+            let n = batch.len();
+            let embeddings = lock_model(&handle.0).embed(batch, Some(n)).map_err(|e| e.to_string())?;
+            copy_rows(out, offset, dim, &embeddings)?;
+            Ok::<Vec<()>, String>(Vec::new())
+        });
+
+        match result {
+            Ok(_) => true,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error("Image embedding failed");
+                    }
+                }
+                false
+            }
+        }
+    })
 }
 
 #[no_mangle]
-pub extern "C" fn fastembed_text_embedding_free(handle: *mut TextEmbeddingHandle) {
-    if !handle.is_null() {
-        unsafe {
-            let _ = Box::from_raw(handle);
+pub extern "C" fn fastembed_image_embedding_free(handle: *mut ImageEmbeddingHandle) {
+    guard("fastembed_image_embedding_free", ptr::null_mut(), (), || {
+        if !handle.is_null() {
+            unsafe {
+                let _ = Box::from_raw(handle);
+            }
         }
-    }
+    })
 }
 
-// Sparse Text Embedding Functions
 #[no_mangle]
-pub extern "C" fn fastembed_sparse_text_embedding_new(
+pub extern "C" fn fastembed_image_embedding_details(
+    handle: *const ImageEmbeddingHandle,
+    details: *mut FastEmbedModelDetails,
+) {
+    guard("fastembed_image_embedding_details", ptr::null_mut(), (), || {
+        if let Some(handle) = unsafe { handle.as_ref() } {
+            handle.1.write_to(details);
+        }
+    })
+}
+
+// Text Rerank Functions
+#[no_mangle]
+pub extern "C" fn fastembed_text_rerank_new(
     model_name: *const c_char,
+    options: *const FastEmbedInitOptions,
     error: *mut *mut FastEmbedError,
-) -> *mut SparseTextEmbeddingHandle {
-    let model_str = unsafe {
-        if model_name.is_null() {
-            "Qdrant/Splade_PP_en_v1"
-        } else {
-            match CStr::from_ptr(model_name).to_str() {
-                Ok(s) => s,
-                Err(e) => {
-                    if !error.is_null() {
-                        *error = FastEmbedError::from_string(format!("Invalid model name: {}", e));
+) -> *mut TextRerankHandle {
+    guard("fastembed_text_rerank_new", error, ptr::null_mut(), || {
+        let options = match ModelOptions::from_ptr(options) {
+            Ok(o) => o,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::from_string(e);
                     }
-                    return ptr::null_mut();
                 }
+                return ptr::null_mut();
             }
-        }
-    };
+        };
+
+        let model_str = unsafe {
+            if model_name.is_null() {
+                DEFAULT_RERANK_MODEL
+            } else {
+                match CStr::from_ptr(model_name).to_str() {
+                    Ok(s) => s,
+                    Err(e) => {
+                        if !error.is_null() {
+                            *error = FastEmbedError::from_string(format!("Invalid model name: {}", e));
+                        }
+                        return ptr::null_mut();
+                    }
+                }
+            }
+        };
 
-    let model = match model_str.parse::<SparseModel>() {
-        Ok(m) => m,
-        Err(e) => {
+        let candidate = match resolve_model(model_str, "text rerank", rerank_candidates()) {
+            Ok(c) => c,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(FASTEMBED_ERROR_UNKNOWN_MODEL, e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let init = options.rerank(candidate.model);
+        if options.offline {
+            if let Err(e) = ensure_cached(&init.cache_dir, &candidate.code, &candidate.files) {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(FASTEMBED_ERROR_MODEL_NOT_CACHED, e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        }
+        if let Err(e) = download_with_options(&options, &init.cache_dir, &candidate.code, &candidate.files) {
             if !error.is_null() {
                 unsafe {
-                    *error = FastEmbedError::from_string(format!("Invalid sparse model: {}", e));
+                    *error = FastEmbedError::with_code(FASTEMBED_ERROR_DOWNLOAD, e);
                 }
             }
             return ptr::null_mut();
         }
-    };
 
-    match SparseTextEmbedding::try_new(SparseInitOptions::new(model)) {
-        Ok(embedding) => Box::into_raw(Box::new(SparseTextEmbeddingHandle(Box::new(embedding)))),
-        Err(e) => {
-            if !error.is_null() {
-                unsafe {
-                    *error = FastEmbedError::from_string(format!("Failed to create sparse text embedding: {}", e));
+        let cache_dir = init.cache_dir.clone();
+        let details = ModelDetails::new(&candidate.code, &cache_dir, candidate.dim, init.max_length);
+        match TextRerank::try_new(init) {
+            Ok(reranker) => {
+                mark_model_used(&cache_dir, &candidate.code);
+                let tokenize = untruncated_tokenizer!(reranker.tokenizer);
+                Box::into_raw(Box::new(TextRerankHandle(Mutex::new(reranker), details, tokenize)))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(
+                            load_error_code(&e),
+                            format!("Failed to create text reranker: {}", e),
+                        );
+                    }
                 }
+                ptr::null_mut()
             }
-            ptr::null_mut()
         }
-    }
+    })
 }
 
 #[no_mangle]
-pub extern "C" fn fastembed_sparse_text_embedding_embed(
-    handle: *mut SparseTextEmbeddingHandle,
-    texts: *const *const c_char,
-    num_texts: usize,
-    batch_size: usize,
+pub extern "C" fn fastembed_text_rerank_new_from_bytes(
+    onnx: *const FastEmbedBytes,
+    tokenizer_files: *const FastEmbedTokenizerBytes,
+    options: *const FastEmbedInitOptions,
     error: *mut *mut FastEmbedError,
-) -> *mut SparseEmbeddingVec {
-    if handle.is_null() || texts.is_null() {
-        if !error.is_null() {
-            unsafe {
-                *error = FastEmbedError::from_string("Null pointer provided".to_string());
+) -> *mut TextRerankHandle {
+    guard("fastembed_text_rerank_new_from_bytes", error, ptr::null_mut(), || {
+        let options = match ModelOptions::from_ptr(options) {
+            Ok(o) => o,
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::from_string(e);
+                    }
+                }
+                return ptr::null_mut();
             }
-        }
-        return ptr::null_mut();
-    }
+        };
 
-    let handle = unsafe { &mut *handle };
-    let text_slice = unsafe { slice::from_raw_parts(texts, num_texts) };
+        let model = match copy_user_defined_bytes(onnx, tokenizer_files) {
+            Ok((onnx_file, tokenizer_files)) => {
+                UserDefinedRerankingModel::new(OnnxSource::Memory(onnx_file), tokenizer_files)
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::from_string(e);
+                    }
+                }
+                return ptr::null_mut();
+            }
+        };
+
+        let init = options.user_defined_rerank();
+        let details = ModelDetails::user_defined(0, init.max_length);
+        match TextRerank::try_new_from_user_defined(model, init) {
+            Ok(reranker) => {
+                let tokenize = untruncated_tokenizer!(reranker.tokenizer);
+                Box::into_raw(Box::new(TextRerankHandle(Mutex::new(reranker), details, tokenize)))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = FastEmbedError::with_code(
+                            load_error_code(&e),
+                            format!("Failed to create text reranker: {}", e),
+                        );
+                    }
+                }
+                ptr::null_mut()
+            }
+        }
+    })
+}
 
-    let mut text_vec = Vec::new();
-    for &text_ptr in text_slice {
-        if text_ptr.is_null() {
+#[no_mangle]
+pub extern "C" fn fastembed_text_rerank_rerank(
+    handle: *mut TextRerankHandle,
+    query: *const c_char,
+    query_len: usize,
+    documents: *const c_char,
+    document_offsets: *const usize,
+    num_documents: usize,
+    return_documents: bool,
+    batch_size: usize,
+    truncated: *mut bool,
+    cancel: *const FastEmbedCancelToken,
+    error: *mut *mut FastEmbedError,
+) -> *mut RerankResultVec {
+    guard("fastembed_text_rerank_rerank", error, ptr::null_mut(), || {
+        if handle.is_null() {
             if !error.is_null() {
                 unsafe {
-                    *error = FastEmbedError::from_string("Null text pointer in array".to_string());
+                    *error = FastEmbedError::from_string("Null pointer provided".to_string());
                 }
             }
             return ptr::null_mut();
         }
-        let text = unsafe { CStr::from_ptr(text_ptr).to_str() };
-        match text {
-            Ok(s) => text_vec.push(s.to_string()),
+
+        let handle = unsafe { &*handle };
+        let inputs = read_string(query, query_len, "query").and_then(|query| {
+            read_packed_strings(documents, document_offsets, num_documents, "document").map(|docs| (query, docs))
+        });
+        let (query_str, doc_strings) = match inputs {
+            Ok(inputs) => inputs,
             Err(e) => {
                 if !error.is_null() {
                     unsafe {
-                        *error = FastEmbedError::from_string(format!("Invalid UTF-8 in text: {}", e));
+                        *error = e.into_error();
                     }
                 }
                 return ptr::null_mut();
             }
-        }
-    }
-
-    let batch_size_opt = if batch_size > 0 { Some(batch_size) } else { None };
+        };
+
+        // Convert Vec<String> to Vec<&str> for the rerank call
+        let doc_vec: Vec<&str> = doc_strings.iter().map(|s| s.as_str()).collect();
+
+        // Each batch is ranked on its own, so shift the indices to the full
+        // document list and sort the combined results by score.
+        let results = run_batches(&doc_vec, batch_size, cancel, |offset, batch| {
+            let n = batch.len();
+            let mut model = lock_model(&handle.0);
+            mark_truncated(
+                truncated,
+                offset,
+                batch.iter().map(|doc| {
+                    model
+                        .tokenizer
+                        .encode((query_str.as_str(), *doc), true)
+                        .map(|encoding| !encoding.get_overflowing().is_empty())
+                }),
+            )?;
+            model
+                .rerank(query_str.as_str(), batch, return_documents, Some(n))
+                .map(|results| {
+                    results
+                        .into_iter()
+                        .map(|mut r| {
+                            r.index += offset;
+                            r
+                        })
+                        .collect::<Vec<_>>()
+                })
+                .map_err(|e| e.to_string())
+        })
+        .map(|mut results| {
+            results.sort_by(|a, b| b.score.total_cmp(&a.score));
+            results
+        });
 
-    match handle.0.embed(text_vec, batch_size_opt) {
-        Ok(embeddings) => {
-            let mut sparse_embs: Vec<SparseEmbeddingC> = embeddings
-                .into_iter()
-                .map(|emb| {
-                    let mut indices_vec = emb.indices;
-                    let mut values_vec = emb.values;
-                    let len = indices_vec.len();
-                    let indices_ptr = indices_vec.as_mut_ptr();
-                    let values_ptr = values_vec.as_mut_ptr();
-                    std::mem::forget(indices_vec);
-                    std::mem::forget(values_vec);
-                    SparseEmbeddingC {
-                        indices: indices_ptr,
-                        values: values_ptr,
-                        len,
+        match results {
+            Ok(results) => {
+                let mut c_results: Vec<RerankResultC> = results
+                    .into_iter()
+                    .map(|r| RerankResultC {
+                        index: r.index,
+                        score: r.score,
+                        // NULL for documents containing NUL bytes, which a C
+                        // string cannot hold
+                        document: r.document
+                            .and_then(|d| CString::new(d).ok())
+                            .map_or(ptr::null_mut(), CString::into_raw),
+                    })
+                    .collect();
+
+                let len = c_results.len();
+                let results_ptr = c_results.as_mut_ptr();
+                std::mem::forget(c_results);
+
+                Box::into_raw(Box::new(RerankResultVec {
+                    results: results_ptr,
+                    len,
+                }))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error("Reranking failed");
                     }
-                })
-                .collect();
+                }
+                ptr::null_mut()
+            }
+        }
+    })
+}
 
-            let len = sparse_embs.len();
-            let embeddings_ptr = sparse_embs.as_mut_ptr();
-            std::mem::forget(sparse_embs);
+#[no_mangle]
+pub extern "C" fn fastembed_text_rerank_free(handle: *mut TextRerankHandle) {
+    guard("fastembed_text_rerank_free", ptr::null_mut(), (), || {
+        if !handle.is_null() {
+            unsafe {
+                let _ = Box::from_raw(handle);
+            }
+        }
+    })
+}
 
-            Box::into_raw(Box::new(SparseEmbeddingVec {
-                embeddings: embeddings_ptr,
-                len,
-            }))
+#[no_mangle]
+pub extern "C" fn fastembed_text_rerank_details(
+    handle: *const TextRerankHandle,
+    details: *mut FastEmbedModelDetails,
+) {
+    guard("fastembed_text_rerank_details", ptr::null_mut(), (), || {
+        if let Some(handle) = unsafe { handle.as_ref() } {
+            handle.1.write_to(details);
         }
-        Err(e) => {
-            if !error.is_null() {
-                unsafe {
-                    *error = FastEmbedError::from_string(format!("Sparse embedding failed: {}", e));
-                }
-            }
-            ptr::null_mut()
+    })
+}
+
+// Late-Interaction Text Embedding Functions
+
+/// ColBERT marks queries and documents with these tokens, placed after [CLS].
+const QUERY_MARKER: &str = "[unused0]";
+const DOCUMENT_MARKER: &str = "[unused1]";
+/// Queries shorter than this many tokens are padded with [MASK] tokens,
+/// which ColBERT uses to expand the query.
+const MIN_QUERY_TOKENS: usize = 32;
+
+/// Outputs holding the token embeddings of a late-interaction model.
+const LATE_INTERACTION_OUTPUTS: &[OutputKey] =
+    &[OutputKey::OnlyOne, OutputKey::ByName("last_hidden_state")];
+
+/// Registers the ColBERT marker tokens as special tokens of a tokenizer.json,
+/// so that a marker in front of a text is encoded as a single token instead
+/// of being split like ordinary text.
+fn add_marker_tokens(tokenizer_file: &[u8]) -> Result<Vec<u8>, String> {
+    let mut tokenizer: serde_json::Value =
+        serde_json::from_slice(tokenizer_file).map_err(|e| format!("Invalid tokenizer file: {}", e))?;
+    let mut markers = Vec::new();
+    for token in [QUERY_MARKER, DOCUMENT_MARKER, "[MASK]"] {
+        let id = tokenizer
+            .pointer(&format!("/model/vocab/{}", token))
+            .and_then(serde_json::Value::as_u64)
+            .ok_or_else(|| format!("Tokenizer has no {} token, which ColBERT models need", token))?;
+        markers.push((token, id));
+    }
+    let added_tokens = tokenizer
+        .get_mut("added_tokens")
+        .and_then(serde_json::Value::as_array_mut)
+        .ok_or_else(|| "Tokenizer file has no added_tokens".to_string())?;
+    for (token, id) in markers {
+        if added_tokens.iter().any(|t| t["content"] == token) {
+            continue;
         }
+        added_tokens.push(serde_json::json!({
+            "id": id,
+            "content": token,
+            "single_word": false,
+            "lstrip": false,
+            "rstrip": false,
+            "normalized": false,
+            "special": true,
+        }));
     }
+    serde_json::to_vec(&tokenizer).map_err(|e| format!("Failed to update tokenizer file: {}", e))
 }
 
-#[no_mangle]
-pub extern "C" fn fastembed_sparse_text_embedding_free(handle: *mut SparseTextEmbeddingHandle) {
-    if !handle.is_null() {
-        unsafe {
-            let _ = Box::from_raw(handle);
+/// Returns the token embeddings of every text in the batches, skipping
+/// padding tokens and L2-normalizing each vector.
+fn token_embeddings(batches: &[SingleBatchOutput]) -> anyhow::Result<Vec<Vec<Vec<f32>>>> {
+    let mut texts = Vec::new();
+    for batch in batches {
+        let hidden = batch.select_output(&LATE_INTERACTION_OUTPUTS)?;
+        let shape = hidden.shape().to_vec();
+        if shape.len() != 3 {
+            anyhow::bail!("Expected token embeddings of shape [batch, tokens, dim], got {:?}", shape);
+        }
+        let (tokens, dim) = (shape[1], shape[2]);
+        let hidden: Vec<f32> = hidden.iter().copied().collect();
+        let mask: Vec<i64> = batch.attention_mask_array.iter().copied().collect();
+        for (text, text_mask) in mask.chunks(tokens).enumerate() {
+            let vectors = text_mask
+                .iter()
+                .enumerate()
+                .filter(|(_, attended)| **attended != 0)
+                .map(|(token, _)| {
+                    let start = (text * tokens + token) * dim;
+                    let mut vector = hidden[start..start + dim].to_vec();
+                    let norm = vector.iter().map(|x| x * x).sum::<f32>().sqrt();
+                    if norm > 0.0 {
+                        vector.iter_mut().for_each(|x| *x /= norm);
+                    }
+                    vector
+                })
+                .collect();
+            texts.push(vectors);
         }
     }
+    Ok(texts)
 }
 
-// Image Embedding Functions
-#[no_mangle]
-pub extern "C" fn fastembed_image_embedding_new(
-    model_name: *const c_char,
+/// Embeds a batch of queries or documents into per-token vectors, marking
+/// them the way ColBERT models are trained to expect.
+fn embed_late_interaction(
+    model: &mut TextEmbedding,
+    texts: Vec<String>,
+    is_query: bool,
+) -> anyhow::Result<Vec<Vec<Vec<f32>>>> {
+    let texts = texts
+        .into_iter()
+        .map(|text| {
+            if !is_query {
+                return Ok(format!("{} {}", DOCUMENT_MARKER, text));
+            }
+            let text = format!("{} {}", QUERY_MARKER, text);
+            let len = model
+                .tokenizer
+                .encode(text.as_str(), true)
+                .map_err(|e| anyhow::anyhow!("Tokenization failed: {}", e))?
+                .len();
+            Ok(text + &" [MASK]".repeat(MIN_QUERY_TOKENS.saturating_sub(len)))
+        })
+        .collect::<anyhow::Result<Vec<String>>>()?;
+    let n = texts.len();
+    model.transform(texts, Some(n))?.export_with_transformer(token_embeddings)
+}
+
+/// Creates a late-interaction text embedding handle from user-defined model
+/// files.
+fn new_late_interaction_text_embedding(
+    files: Result<(Vec<u8>, TokenizerFiles), String>,
+    options: *const FastEmbedInitOptions,
     error: *mut *mut FastEmbedError,
-) -> *mut ImageEmbeddingHandle {
-    let model_str = unsafe {
-        if model_name.is_null() {
-            "Qdrant/clip-ViT-B-32-vision"
-        } else {
-            match CStr::from_ptr(model_name).to_str() {
-                Ok(s) => s,
-                Err(e) => {
-                    if !error.is_null() {
-                        *error = FastEmbedError::from_string(format!("Invalid model name: {}", e));
-                    }
-                    return ptr::null_mut();
+) -> *mut LateInteractionTextEmbeddingHandle {
+    let options = match ModelOptions::from_ptr(options) {
+        Ok(o) => o,
+        Err(e) => {
+            if !error.is_null() {
+                unsafe {
+                    *error = FastEmbedError::from_string(e);
                 }
             }
+            return ptr::null_mut();
         }
     };
 
-    let model = match model_str.parse::<ImageEmbeddingModel>() {
+    let model = files.and_then(|(onnx, mut tokenizer)| {
+        tokenizer.tokenizer_file = add_marker_tokens(&tokenizer.tokenizer_file)?;
+        user_defined_text_model(onnx, tokenizer, FASTEMBED_POOLING_DEFAULT)
+    });
+    let model = match model {
         Ok(m) => m,
         Err(e) => {
             if !error.is_null() {
                 unsafe {
-                    *error = FastEmbedError::from_string(format!("Invalid image model: {}", e));
+                    *error = FastEmbedError::from_string(e);
                 }
             }
             return ptr::null_mut();
         }
     };
 
-    match ImageEmbedding::try_new(ImageInitOptions::new(model)) {
-        Ok(embedding) => Box::into_raw(Box::new(ImageEmbeddingHandle(Box::new(embedding)))),
+    let init = options.user_defined();
+    let max_length = init.max_length;
+    match TextEmbedding::try_new_from_user_defined(model, init) {
+        Ok(mut embedding) => {
+            // Probe the output dimension, as for user-defined text embeddings
+            let probe = embed_late_interaction(&mut embedding, vec!["dimension probe".to_string()], false)
+                .and_then(|texts| match texts.first().and_then(|t| t.first()) {
+                    Some(vector) if !vector.is_empty() => Ok(vector.len()),
+                    _ => Err(anyhow::anyhow!("model returned no token embeddings")),
+                });
+            let dim = match probe {
+                Ok(dim) => dim,
+                Err(e) => {
+                    if !error.is_null() {
+                        unsafe {
+                            *error = FastEmbedError::with_code(
+                                FASTEMBED_ERROR_INFERENCE,
+                                format!("Failed to run late-interaction model: {}", e),
+                            );
+                        }
+                    }
+                    return ptr::null_mut();
+                }
+            };
+            let details = ModelDetails::user_defined(dim, max_length);
+            Box::into_raw(Box::new(LateInteractionTextEmbeddingHandle(Mutex::new(embedding), details)))
+        }
         Err(e) => {
             if !error.is_null() {
                 unsafe {
-                    *error = FastEmbedError::from_string(format!("Failed to create image embedding: {}", e));
+                    *error = FastEmbedError::with_code(
+                        load_error_code(&e),
+                        format!("Failed to create late-interaction text embedding: {}", e),
+                    );
                 }
             }
             ptr::null_mut()
@@ -401,133 +2578,184 @@ pub extern "C" fn fastembed_image_embedding_new(
 }
 
 #[no_mangle]
-pub extern "C" fn fastembed_image_embedding_embed(
-    handle: *mut ImageEmbeddingHandle,
-    image_paths: *const *const c_char,
-    num_images: usize,
-    batch_size: usize,
+pub extern "C" fn fastembed_late_interaction_text_embedding_new_from_files(
+    onnx_path: *const c_char,
+    tokenizer_files: *const FastEmbedTokenizerPaths,
+    options: *const FastEmbedInitOptions,
     error: *mut *mut FastEmbedError,
-) -> *mut FloatArrayVec {
-    if handle.is_null() || image_paths.is_null() {
-        if !error.is_null() {
-            unsafe {
-                *error = FastEmbedError::from_string("Null pointer provided".to_string());
-            }
-        }
-        return ptr::null_mut();
-    }
+) -> *mut LateInteractionTextEmbeddingHandle {
+    guard("fastembed_late_interaction_text_embedding_new_from_files", error, ptr::null_mut(), || {
+        let files = read_user_defined_files(onnx_path, tokenizer_files);
+        new_late_interaction_text_embedding(files, options, error)
+    })
+}
 
-    let handle = unsafe { &mut *handle };
-    let path_slice = unsafe { slice::from_raw_parts(image_paths, num_images) };
+#[no_mangle]
+pub extern "C" fn fastembed_late_interaction_text_embedding_new_from_bytes(
+    onnx: *const FastEmbedBytes,
+    tokenizer_files: *const FastEmbedTokenizerBytes,
+    options: *const FastEmbedInitOptions,
+    error: *mut *mut FastEmbedError,
+) -> *mut LateInteractionTextEmbeddingHandle {
+    guard("fastembed_late_interaction_text_embedding_new_from_bytes", error, ptr::null_mut(), || {
+        let files = copy_user_defined_bytes(onnx, tokenizer_files);
+        new_late_interaction_text_embedding(files, options, error)
+    })
+}
 
-    let mut path_vec = Vec::new();
-    for &path_ptr in path_slice {
-        if path_ptr.is_null() {
+/// Embeds texts into one array per text holding its token vectors row after
+/// row.
+#[no_mangle]
+pub extern "C" fn fastembed_late_interaction_text_embedding_embed(
+    handle: *const LateInteractionTextEmbeddingHandle,
+    texts: *const c_char,
+    text_offsets: *const usize,
+    num_texts: usize,
+    is_query: bool,
+    batch_size: usize,
+    cancel: *const FastEmbedCancelToken,
+    error: *mut *mut FastEmbedError,
+) -> *mut FloatArrayVec {
+    guard("fastembed_late_interaction_text_embedding_embed", error, ptr::null_mut(), || {
+        if handle.is_null() {
             if !error.is_null() {
                 unsafe {
-                    *error = FastEmbedError::from_string("Null path pointer in array".to_string());
+                    *error = FastEmbedError::from_string("Null pointer provided".to_string());
                 }
             }
             return ptr::null_mut();
         }
-        let path = unsafe { CStr::from_ptr(path_ptr).to_str() };
-        match path {
-            Ok(s) => path_vec.push(s.to_string()),
+
+        let handle = unsafe { &*handle };
+        let text_vec = match read_packed_strings(texts, text_offsets, num_texts, "text") {
+            Ok(strings) => strings,
             Err(e) => {
                 if !error.is_null() {
                     unsafe {
-                        *error = FastEmbedError::from_string(format!("Invalid UTF-8 in path: {}", e));
+                        *error = e.into_error();
                     }
                 }
                 return ptr::null_mut();
             }
-        }
-    }
-
-    let batch_size_opt = if batch_size > 0 { Some(batch_size) } else { None };
+        };
 
-    match handle.0.embed(path_vec, batch_size_opt) {
-        Ok(embeddings) => {
-            let mut arrays: Vec<FloatArray> = embeddings
-                .into_iter()
-                .map(|emb| {
-                    let mut boxed_slice = emb.into_boxed_slice();
-                    let len = boxed_slice.len();
-                    let data = boxed_slice.as_mut_ptr();
-                    std::mem::forget(boxed_slice);
-                    FloatArray { data, len }
-                })
-                .collect();
-
-            let len = arrays.len();
-            let arrays_ptr = arrays.as_mut_ptr();
-            std::mem::forget(arrays);
+        let embeddings = run_batches(&text_vec, batch_size, cancel, |_, batch| {
+            embed_late_interaction(&mut lock_model(&handle.0), batch, is_query)
+        });
 
-            Box::into_raw(Box::new(FloatArrayVec {
-                arrays: arrays_ptr,
-                len,
-            }))
-        }
-        Err(e) => {
-            if !error.is_null() {
-                unsafe {
-                    *error = FastEmbedError::from_string(format!("Image embedding failed: {}", e));
+        match embeddings {
+            Ok(embeddings) => {
+                let mut arrays: Vec<FloatArray> = embeddings
+                    .into_iter()
+                    .map(|vectors| {
+                        let mut boxed_slice = vectors.concat().into_boxed_slice();
+                        let len = boxed_slice.len();
+                        let data = boxed_slice.as_mut_ptr();
+                        std::mem::forget(boxed_slice);
+                        FloatArray { data, len }
+                    })
+                    .collect();
+
+                let len = arrays.len();
+                let arrays_ptr = arrays.as_mut_ptr();
+                std::mem::forget(arrays);
+
+                Box::into_raw(Box::new(FloatArrayVec {
+                    arrays: arrays_ptr,
+                    len,
+                }))
+            }
+            Err(e) => {
+                if !error.is_null() {
+                    unsafe {
+                        *error = e.into_error("Embedding failed");
+                    }
                 }
+                ptr::null_mut()
             }
-            ptr::null_mut()
         }
-    }
+    })
 }
 
 #[no_mangle]
-pub extern "C" fn fastembed_image_embedding_free(handle: *mut ImageEmbeddingHandle) {
-    if !handle.is_null() {
-        unsafe {
-            let _ = Box::from_raw(handle);
+pub extern "C" fn fastembed_late_interaction_text_embedding_free(handle: *mut LateInteractionTextEmbeddingHandle) {
+    guard("fastembed_late_interaction_text_embedding_free", ptr::null_mut(), (), || {
+        if !handle.is_null() {
+            unsafe {
+                let _ = Box::from_raw(handle);
+            }
         }
-    }
+    })
 }
 
-// Text Rerank Functions
 #[no_mangle]
-pub extern "C" fn fastembed_text_rerank_new(
-    model_name: *const c_char,
-    error: *mut *mut FastEmbedError,
-) -> *mut TextRerankHandle {
-    let model_str = unsafe {
-        if model_name.is_null() {
-            "BAAI/bge-reranker-base"
-        } else {
-            match CStr::from_ptr(model_name).to_str() {
-                Ok(s) => s,
-                Err(e) => {
-                    if !error.is_null() {
-                        *error = FastEmbedError::from_string(format!("Invalid model name: {}", e));
-                    }
-                    return ptr::null_mut();
-                }
-            }
+pub extern "C" fn fastembed_late_interaction_text_embedding_details(
+    handle: *const LateInteractionTextEmbeddingHandle,
+    details: *mut FastEmbedModelDetails,
+) {
+    guard("fastembed_late_interaction_text_embedding_details", ptr::null_mut(), (), || {
+        if let Some(handle) = unsafe { handle.as_ref() } {
+            handle.1.write_to(details);
         }
-    };
+    })
+}
 
-    let model = match model_str.parse::<RerankerModel>() {
-        Ok(m) => m,
-        Err(e) => {
-            if !error.is_null() {
-                unsafe {
-                    *error = FastEmbedError::from_string(format!("Invalid reranker model: {}", e));
-                }
-            }
-            return ptr::null_mut();
+// Tokenizer access
+
+/// Tokens of one text, packed like the inputs: token i is the bytes
+/// `tokens[token_offsets[i]..token_offsets[i + 1]]`, and covers the bytes
+/// `offsets[2 * i]..offsets[2 * i + 1]` of the text.
+#[repr(C)]
+pub struct FastEmbedTokens {
+    pub ids: *mut u32,
+    pub offsets: *mut usize,
+    pub tokens: *mut c_char,
+    pub token_offsets: *mut usize,
+    pub len: usize,
+}
+
+impl FastEmbedTokens {
+    fn new(ids: &[u32], tokens: &[String], offsets: &[(usize, usize)]) -> *mut FastEmbedTokens {
+        let mut data = Vec::new();
+        let mut token_offsets = Vec::with_capacity(tokens.len() + 1);
+        token_offsets.push(0);
+        for token in tokens {
+            data.extend_from_slice(token.as_bytes());
+            token_offsets.push(data.len());
         }
-    };
+        let offsets: Vec<usize> = offsets.iter().flat_map(|&(start, end)| [start, end]).collect();
+        Box::into_raw(Box::new(FastEmbedTokens {
+            ids: Box::into_raw(ids.to_vec().into_boxed_slice()) as *mut u32,
+            offsets: Box::into_raw(offsets.into_boxed_slice()) as *mut usize,
+            tokens: Box::into_raw(data.into_boxed_slice()) as *mut c_char,
+            token_offsets: Box::into_raw(token_offsets.into_boxed_slice()) as *mut usize,
+            len: ids.len(),
+        }))
+    }
+}
 
-    match TextRerank::try_new(RerankInitOptions::new(model)) {
-        Ok(reranker) => Box::into_raw(Box::new(TextRerankHandle(Box::new(reranker)))),
+/// Tokenizes `text` with the tokenizer of a handle, which is `None` if the
+/// handle is null, and hands the tokens to the caller.
+fn tokenize_text(
+    tokenize: Option<&TokenizeFn>,
+    text: *const c_char,
+    text_len: usize,
+    error: *mut *mut FastEmbedError,
+) -> *mut FastEmbedTokens {
+    let result = tokenize
+        .ok_or_else(|| FastEmbedError::from_string("Null pointer provided".to_string()))
+        .and_then(|tokenize| {
+            let text = read_string(text, text_len, "text").map_err(InvalidInput::into_error)?;
+            tokenize(text.as_str()).map_err(|e| FastEmbedError::from_string(format!("Tokenization failed: {}", e)))
+        });
+    match result {
+        Ok(tokens) => FastEmbedTokens::new(&tokens.ids, &tokens.tokens, &tokens.offsets),
         Err(e) => {
-            if !error.is_null() {
+            if error.is_null() {
+                fastembed_error_free(e);
+            } else {
                 unsafe {
-                    *error = FastEmbedError::from_string(format!("Failed to create text reranker: {}", e));
+                    *error = e;
                 }
             }
             ptr::null_mut()
@@ -535,157 +2763,213 @@ pub extern "C" fn fastembed_text_rerank_new(
     }
 }
 
-#[no_mangle]
-pub extern "C" fn fastembed_text_rerank_rerank(
-    handle: *mut TextRerankHandle,
-    query: *const c_char,
-    documents: *const *const c_char,
-    num_documents: usize,
-    return_documents: bool,
-    batch_size: usize,
+/// Counts the tokens of packed texts with the tokenizer of a handle, which is
+/// `None` if the handle is null, into `counts`, which holds `num_texts`
+/// entries.
+fn count_text_tokens(
+    tokenize: Option<&TokenizeFn>,
+    texts: *const c_char,
+    text_offsets: *const usize,
+    num_texts: usize,
+    counts: *mut usize,
     error: *mut *mut FastEmbedError,
-) -> *mut RerankResultVec {
-    if handle.is_null() || query.is_null() || documents.is_null() {
-        if !error.is_null() {
-            unsafe {
-                *error = FastEmbedError::from_string("Null pointer provided".to_string());
-            }
-        }
-        return ptr::null_mut();
-    }
-
-    let handle = unsafe { &mut *handle };
-    let query_str = unsafe {
-        match CStr::from_ptr(query).to_str() {
-            Ok(s) => s,
-            Err(e) => {
-                if !error.is_null() {
-                    *error = FastEmbedError::from_string(format!("Invalid UTF-8 in query: {}", e));
-                }
-                return ptr::null_mut();
+) -> bool {
+    let result = tokenize
+        .ok_or_else(|| FastEmbedError::from_string("Null pointer provided".to_string()))
+        .and_then(|tokenize| {
+            let texts = read_packed_strings(texts, text_offsets, num_texts, "text")
+                .and_then(|texts| {
+                    if counts.is_null() && num_texts > 0 {
+                        return Err(InvalidInput::new("Null counts buffer provided".to_string()));
+                    }
+                    Ok(texts)
+                })
+                .map_err(InvalidInput::into_error)?;
+            texts
+                .iter()
+                .map(|text| tokenize(text.as_str()).map(|tokens| tokens.ids.len()))
+                .collect::<Result<Vec<usize>, String>>()
+                .map_err(|e| FastEmbedError::from_string(format!("Tokenization failed: {}", e)))
+        });
+    match result {
+        Ok(n) => {
+            if !n.is_empty() {
+                unsafe { slice::from_raw_parts_mut(counts, n.len()) }.copy_from_slice(&n);
             }
+            true
         }
-    };
-
-    let doc_slice = unsafe { slice::from_raw_parts(documents, num_documents) };
-    let mut doc_strings = Vec::new();
-    for &doc_ptr in doc_slice {
-        if doc_ptr.is_null() {
-            if !error.is_null() {
+        Err(e) => {
+            if error.is_null() {
+                fastembed_error_free(e);
+            } else {
                 unsafe {
-                    *error = FastEmbedError::from_string("Null document pointer in array".to_string());
-                }
-            }
-            return ptr::null_mut();
-        }
-        let doc = unsafe { CStr::from_ptr(doc_ptr).to_str() };
-        match doc {
-            Ok(s) => doc_strings.push(s.to_string()),
-            Err(e) => {
-                if !error.is_null() {
-                    unsafe {
-                        *error = FastEmbedError::from_string(format!("Invalid UTF-8 in document: {}", e));
-                    }
+                    *error = e;
                 }
-                return ptr::null_mut();
             }
+            false
         }
     }
+}
 
-    let batch_size_opt = if batch_size > 0 { Some(batch_size) } else { None };
-    
-    // Convert Vec<String> to Vec<&str> for the rerank call
-    let doc_vec: Vec<&str> = doc_strings.iter().map(|s| s.as_str()).collect();
+// The *_tokenize and *_count_tokens functions below use the untruncated
+// tokenizer stored in the handle, so they report every token of a text
+// without blocking calls on the model while they run.
 
-    match handle.0.rerank(query_str, doc_vec, return_documents, batch_size_opt) {
-        Ok(results) => {
-            let mut c_results: Vec<RerankResultC> = results
-                .into_iter()
-                .map(|r| RerankResultC {
-                    index: r.index,
-                    score: r.score,
-                    document: r.document
-                        .map(|d| CString::new(d).unwrap().into_raw())
-                        .unwrap_or(ptr::null_mut()),
-                })
-                .collect();
+#[no_mangle]
+pub extern "C" fn fastembed_text_embedding_tokenize(
+    handle: *const TextEmbeddingHandle,
+    text: *const c_char,
+    text_len: usize,
+    error: *mut *mut FastEmbedError,
+) -> *mut FastEmbedTokens {
+    guard("fastembed_text_embedding_tokenize", error, ptr::null_mut(), || {
+        let tokenize = unsafe { handle.as_ref() }.map(|handle| &handle.2);
+        tokenize_text(tokenize, text, text_len, error)
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_text_embedding_count_tokens(
+    handle: *const TextEmbeddingHandle,
+    texts: *const c_char,
+    text_offsets: *const usize,
+    num_texts: usize,
+    counts: *mut usize,
+    error: *mut *mut FastEmbedError,
+) -> bool {
+    guard("fastembed_text_embedding_count_tokens", error, false, || {
+        let tokenize = unsafe { handle.as_ref() }.map(|handle| &handle.2);
+        count_text_tokens(tokenize, texts, text_offsets, num_texts, counts, error)
+    })
+}
 
-            let len = c_results.len();
-            let results_ptr = c_results.as_mut_ptr();
-            std::mem::forget(c_results);
+#[no_mangle]
+pub extern "C" fn fastembed_sparse_text_embedding_tokenize(
+    handle: *const SparseTextEmbeddingHandle,
+    text: *const c_char,
+    text_len: usize,
+    error: *mut *mut FastEmbedError,
+) -> *mut FastEmbedTokens {
+    guard("fastembed_sparse_text_embedding_tokenize", error, ptr::null_mut(), || {
+        let tokenize = unsafe { handle.as_ref() }.map(|handle| &handle.2);
+        tokenize_text(tokenize, text, text_len, error)
+    })
+}
 
-            Box::into_raw(Box::new(RerankResultVec {
-                results: results_ptr,
-                len,
-            }))
-        }
-        Err(e) => {
-            if !error.is_null() {
-                unsafe {
-                    *error = FastEmbedError::from_string(format!("Reranking failed: {}", e));
-                }
-            }
-            ptr::null_mut()
-        }
-    }
+#[no_mangle]
+pub extern "C" fn fastembed_sparse_text_embedding_count_tokens(
+    handle: *const SparseTextEmbeddingHandle,
+    texts: *const c_char,
+    text_offsets: *const usize,
+    num_texts: usize,
+    counts: *mut usize,
+    error: *mut *mut FastEmbedError,
+) -> bool {
+    guard("fastembed_sparse_text_embedding_count_tokens", error, false, || {
+        let tokenize = unsafe { handle.as_ref() }.map(|handle| &handle.2);
+        count_text_tokens(tokenize, texts, text_offsets, num_texts, counts, error)
+    })
 }
 
 #[no_mangle]
-pub extern "C" fn fastembed_text_rerank_free(handle: *mut TextRerankHandle) {
-    if !handle.is_null() {
+pub extern "C" fn fastembed_text_rerank_tokenize(
+    handle: *const TextRerankHandle,
+    text: *const c_char,
+    text_len: usize,
+    error: *mut *mut FastEmbedError,
+) -> *mut FastEmbedTokens {
+    guard("fastembed_text_rerank_tokenize", error, ptr::null_mut(), || {
+        let tokenize = unsafe { handle.as_ref() }.map(|handle| &handle.2);
+        tokenize_text(tokenize, text, text_len, error)
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_text_rerank_count_tokens(
+    handle: *const TextRerankHandle,
+    texts: *const c_char,
+    text_offsets: *const usize,
+    num_texts: usize,
+    counts: *mut usize,
+    error: *mut *mut FastEmbedError,
+) -> bool {
+    guard("fastembed_text_rerank_count_tokens", error, false, || {
+        let tokenize = unsafe { handle.as_ref() }.map(|handle| &handle.2);
+        count_text_tokens(tokenize, texts, text_offsets, num_texts, counts, error)
+    })
+}
+
+#[no_mangle]
+pub extern "C" fn fastembed_tokens_free(tokens: *mut FastEmbedTokens) {
+    guard("fastembed_tokens_free", ptr::null_mut(), (), || {
+        if tokens.is_null() {
+            return;
+        }
         unsafe {
-            let _ = Box::from_raw(handle);
+            let tokens = Box::from_raw(tokens);
+            let len = tokens.len;
+            let data_len = *tokens.token_offsets.add(len);
+            drop(Box::from_raw(ptr::slice_from_raw_parts_mut(tokens.ids, len)));
+            drop(Box::from_raw(ptr::slice_from_raw_parts_mut(tokens.offsets, 2 * len)));
+            drop(Box::from_raw(ptr::slice_from_raw_parts_mut(tokens.tokens, data_len)));
+            drop(Box::from_raw(ptr::slice_from_raw_parts_mut(tokens.token_offsets, len + 1)));
         }
-    }
+    })
 }
 
 // Memory cleanup functions
 #[no_mangle]
 pub extern "C" fn fastembed_float_array_vec_free(vec: *mut FloatArrayVec) {
-    if !vec.is_null() {
-        unsafe {
-            let vec = Box::from_raw(vec);
-            let arrays = Vec::from_raw_parts(vec.arrays, vec.len, vec.len);
-            for array in arrays {
-                if !array.data.is_null() {
-                    let _ = Vec::from_raw_parts(array.data, array.len, array.len);
+    guard("fastembed_float_array_vec_free", ptr::null_mut(), (), || {
+        if !vec.is_null() {
+            unsafe {
+                let vec = Box::from_raw(vec);
+                let arrays = Vec::from_raw_parts(vec.arrays, vec.len, vec.len);
+                for array in arrays {
+                    if !array.data.is_null() {
+                        let _ = Vec::from_raw_parts(array.data, array.len, array.len);
+                    }
                 }
             }
         }
-    }
+    })
 }
 
 #[no_mangle]
 pub extern "C" fn fastembed_sparse_embedding_vec_free(vec: *mut SparseEmbeddingVec) {
-    if !vec.is_null() {
-        unsafe {
-            let vec = Box::from_raw(vec);
-            let embeddings = Vec::from_raw_parts(vec.embeddings, vec.len, vec.len);
-            for emb in embeddings {
-                if !emb.indices.is_null() {
-                    let _ = Vec::from_raw_parts(emb.indices, emb.len, emb.len);
-                }
-                if !emb.values.is_null() {
-                    let _ = Vec::from_raw_parts(emb.values, emb.len, emb.len);
+    guard("fastembed_sparse_embedding_vec_free", ptr::null_mut(), (), || {
+        if !vec.is_null() {
+            unsafe {
+                let vec = Box::from_raw(vec);
+                let embeddings = Vec::from_raw_parts(vec.embeddings, vec.len, vec.len);
+                for emb in embeddings {
+                    if !emb.indices.is_null() {
+                        let _ = Vec::from_raw_parts(emb.indices, emb.len, emb.len);
+                    }
+                    if !emb.values.is_null() {
+                        let _ = Vec::from_raw_parts(emb.values, emb.len, emb.len);
+                    }
                 }
             }
         }
-    }
+    })
 }
 
 #[no_mangle]
 pub extern "C" fn fastembed_rerank_result_vec_free(vec: *mut RerankResultVec) {
-    if !vec.is_null() {
-        unsafe {
-            let vec = Box::from_raw(vec);
-            let results = Vec::from_raw_parts(vec.results, vec.len, vec.len);
-            for result in results {
-                if !result.document.is_null() {
-                    let _ = CString::from_raw(result.document);
+    guard("fastembed_rerank_result_vec_free", ptr::null_mut(), (), || {
+        if !vec.is_null() {
+            unsafe {
+                let vec = Box::from_raw(vec);
+                let results = Vec::from_raw_parts(vec.results, vec.len, vec.len);
+                for result in results {
+                    if !result.document.is_null() {
+                        let _ = CString::from_raw(result.document);
+                    }
                 }
             }
         }
-    }
+    })
 }
 
 // Model Information Structures
@@ -694,6 +2978,14 @@ pub struct ModelInfoC {
     pub model_code: *mut c_char,
     pub description: *mut c_char,
     pub dim: usize,
+    pub pooling: i32,
+    pub max_tokens: usize,
+    pub query_prefix: *mut c_char,
+    pub passage_prefix: *mut c_char,
+    pub model_file: *mut c_char,
+    pub approx_size: u64,
+    pub quantized: bool,
+    pub multilingual: bool,
 }
 
 #[repr(C)]
@@ -702,26 +2994,240 @@ pub struct ModelInfoVec {
     pub len: usize,
 }
 
-// Text Embedding Model Listing
-#[no_mangle]
-pub extern "C" fn fastembed_text_embedding_list_supported_models() -> *mut ModelInfoVec {
-    let models = TextEmbedding::list_supported_models();
-    let mut model_infos = Vec::new();
-
-    for model in models {
-        let model_code = CString::new(model.model_code).unwrap_or_else(|_| CString::new("").unwrap());
-        let description = CString::new(model.description).unwrap_or_else(|_| CString::new("").unwrap());
-        
-        model_infos.push(ModelInfoC {
-            model_code: model_code.into_raw(),
-            description: description.into_raw(),
-            dim: model.dim,
-        });
+/// Model metadata that fastembed-rs does not provide, taken from the model
+/// cards. Entries match a lowercase substring of the model code, so the
+/// quantized variants of a model share its entry. Token limits and sizes
+/// read from a cached model take precedence.
+struct ModelTraits {
+    pattern: &'static str,
+    max_tokens: usize,
+    query_prefix: &'static str,
+    passage_prefix: &'static str,
+    multilingual: bool,
+    // Approximate size of the full precision ONNX file in MB, 0 if unknown
+    size_mb: u64,
+}
+
+const BGE_EN_QUERY: &str = "Represent this sentence for searching relevant passages: ";
+const BGE_ZH_QUERY: &str = "为这个句子生成表示以用于检索相关文章：";
+
+const fn traits(
+    pattern: &'static str,
+    max_tokens: usize,
+    query_prefix: &'static str,
+    passage_prefix: &'static str,
+    multilingual: bool,
+    size_mb: u64,
+) -> ModelTraits {
+    ModelTraits {
+        pattern,
+        max_tokens,
+        query_prefix,
+        passage_prefix,
+        multilingual,
+        size_mb,
+    }
+}
+
+const MODEL_TRAITS: &[ModelTraits] = &[
+    // Text embeddings
+    traits("all-minilm-l6-v2", 512, "", "", false, 90),
+    traits("all-minilm-l12-v2", 512, "", "", false, 133),
+    traits("bge-small-en-v1.5", 512, BGE_EN_QUERY, "", false, 133),
+    traits("bge-base-en-v1.5", 512, BGE_EN_QUERY, "", false, 438),
+    traits("bge-large-en-v1.5", 512, BGE_EN_QUERY, "", false, 1340),
+    traits("bge-small-zh-v1.5", 512, BGE_ZH_QUERY, "", false, 96),
+    traits("bge-large-zh-v1.5", 512, BGE_ZH_QUERY, "", false, 1300),
+    traits("nomic-embed-text-v1", 8192, "search_query: ", "search_document: ", false, 547),
+    traits("paraphrase-multilingual-minilm-l12-v2", 512, "", "", true, 470),
+    traits("paraphrase-multilingual-mpnet-base-v2", 512, "", "", true, 1110),
+    traits("multilingual-e5-small", 512, "query: ", "passage: ", true, 470),
+    traits("multilingual-e5-base", 512, "query: ", "passage: ", true, 1110),
+    traits("multilingual-e5-large", 512, "query: ", "passage: ", true, 2240),
+    traits("mxbai-embed-large-v1", 512, BGE_EN_QUERY, "", false, 1340),
+    traits("gte-base-en-v1.5", 8192, "", "", false, 547),
+    traits("gte-large-en-v1.5", 8192, "", "", false, 1740),
+    traits("modernbert-embed-large", 8192, "search_query: ", "search_document: ", false, 1580),
+    traits("clip-vit-b-32-text", 77, "", "", false, 254),
+    traits("jina-embeddings-v2-base-code", 8192, "", "", false, 642),
+    traits("jina-embeddings-v2-base-en", 8192, "", "", false, 547),
+    traits("jina-embeddings-v2", 8192, "", "", false, 0),
+    traits("all-mpnet-base-v2", 512, "", "", false, 438),
+    traits("snowflake-arctic-embed", 512, BGE_EN_QUERY, "", false, 0),
+    traits("embeddinggemma-300m", 2048, "task: search result | query: ", "title: none | text: ", true, 1230),
+    // Sparse embeddings
+    traits("splade_pp_en_v1", 512, "", "", false, 532),
+    traits("all_minilm_l6_v2_with_attentions", 512, "", "", false, 90),
+    traits("bge-m3", 8192, "", "", true, 2270),
+    // Image embeddings
+    traits("clip-vit-b-32-vision", 0, "", "", false, 352),
+    traits("resnet50", 0, "", "", false, 103),
+    traits("unicom-vit", 0, "", "", false, 0),
+    traits("nomic-embed-vision-v1.5", 0, "", "", false, 375),
+    // Rerankers
+    traits("bge-reranker-base", 512, "", "", false, 1110),
+    traits("bge-reranker-v2-m3", 8192, "", "", true, 2270),
+    traits("jina-reranker-v1-turbo-en", 8192, "", "", false, 151),
+    traits("jina-reranker-v2-base-multilingual", 1024, "", "", true, 1110),
+];
+
+fn model_traits(model_code: &str) -> Option<&'static ModelTraits> {
+    let code = model_code.to_lowercase();
+    MODEL_TRAITS.iter().find(|t| code.contains(t.pattern))
+}
+
+/// Quantized variants carry a "Q" suffix in their fastembed-rs enum name or
+/// ship a quantized ONNX file.
+fn is_quantized(alias: &str, model_file: &str) -> bool {
+    let file = model_file.to_lowercase();
+    alias.ends_with('Q') || file.contains("quantized") || file.contains("int8")
+}
+
+/// Token limit from the config files of a cached model. Tokenizers without
+/// a limit report a huge placeholder such as 1e30, so the model config is
+/// read in that case.
+fn config_max_tokens(snapshot: &Path) -> Option<usize> {
+    let read = |file: &str| {
+        let json = std::fs::read_to_string(snapshot.join(file)).ok()?;
+        serde_json::from_str::<serde_json::Value>(&json).ok()
+    };
+    read("tokenizer_config.json")
+        .and_then(|config| config["model_max_length"].as_f64())
+        .filter(|n| (1.0..=1_000_000.0).contains(n))
+        .map(|n| n as usize)
+        .or_else(|| {
+            read("config.json")
+                .and_then(|config| config["max_position_embeddings"].as_u64())
+                .map(|n| n as usize)
+        })
+}
+
+/// Total size of the ONNX files of a cached model, if all are present.
+fn cached_size(snapshot: &Path, onnx_files: &[String]) -> Option<u64> {
+    onnx_files
+        .iter()
+        .map(|f| std::fs::metadata(snapshot.join(f)).ok().map(|m| m.len()))
+        .sum()
+}
+
+/// Token limit and ONNX size read from the files of a cached model.
+#[derive(Clone, Copy, Default)]
+struct CachedMetadata {
+    max_tokens: Option<usize>,
+    size: Option<u64>,
+}
+
+/// Metadata of complete cached snapshots, which never change, so listing
+/// models reads each snapshot's files only once per process.
+static CACHED_METADATA: OnceLock<Mutex<HashMap<PathBuf, CachedMetadata>>> = OnceLock::new();
+
+/// Metadata of a model cached in `cache_dir`, empty if it is not cached.
+fn cached_metadata(cache_dir: &Path, model_code: &str, onnx_files: &[String]) -> CachedMetadata {
+    let snapshot = match cached_snapshot(cache_dir, model_code) {
+        Some(snapshot) => snapshot,
+        None => return CachedMetadata::default(),
+    };
+    let known = CACHED_METADATA.get_or_init(Default::default);
+    if let Some(metadata) = known.lock().unwrap_or_else(PoisonError::into_inner).get(&snapshot) {
+        return *metadata;
+    }
+    let metadata = CachedMetadata {
+        max_tokens: config_max_tokens(&snapshot),
+        size: cached_size(&snapshot, onnx_files),
+    };
+    // A snapshot still being downloaded lacks files, so it is read again
+    if metadata.size.is_some() {
+        known.lock().unwrap_or_else(PoisonError::into_inner).insert(snapshot, metadata);
+    }
+    metadata
+}
+
+/// Cache directory a listing function reads model files from: the given
+/// one, or the default cache dir if it is NULL or empty.
+fn listing_cache_dir(cache_dir: *const c_char) -> PathBuf {
+    optional_string(cache_dir, "cache directory")
+        .ok()
+        .flatten()
+        .map_or_else(default_cache_dir, PathBuf::from)
+}
+
+/// The ONNX file of a model and its external data files, if any.
+fn onnx_files(model_file: &str, additional_files: &[String]) -> Vec<String> {
+    let mut files = vec![model_file.to_string()];
+    files.extend(additional_files.iter().cloned());
+    files
+}
+
+fn pooling_to_c(pooling: Option<Pooling>) -> i32 {
+    match pooling {
+        Some(Pooling::Cls) => FASTEMBED_POOLING_CLS,
+        Some(Pooling::Mean) => FASTEMBED_POOLING_MEAN,
+        _ => FASTEMBED_POOLING_DEFAULT,
+    }
+}
+
+fn c_string(s: &str) -> *mut c_char {
+    CString::new(s)
+        .unwrap_or_else(|_| CString::new("").unwrap())
+        .into_raw()
+}
+
+impl ModelInfoC {
+    /// Describes a supported model. Models cached in `cache_dir` report the
+    /// token limit from their config and the size of their files; others
+    /// use the model card values. Text models missing from both fall back to
+    /// default_max_tokens, the max length fastembed-rs truncates inputs to.
+    #[allow(clippy::too_many_arguments)]
+    fn new<T: Debug>(
+        model: &T,
+        model_code: &str,
+        description: &str,
+        dim: usize,
+        onnx_files: &[String],
+        pooling: i32,
+        default_max_tokens: usize,
+        cache_dir: &Path,
+    ) -> ModelInfoC {
+        let model_file = onnx_files.first().map_or("", String::as_str);
+        let quantized = is_quantized(&format!("{:?}", model), model_file);
+        let traits = model_traits(model_code);
+        let cached = cached_metadata(cache_dir, model_code, onnx_files);
+
+        // Image models have no token limit
+        let max_tokens = if default_max_tokens == 0 {
+            0
+        } else {
+            cached
+                .max_tokens
+                .or(traits.map(|t| t.max_tokens).filter(|&n| n > 0))
+                .unwrap_or(default_max_tokens)
+        };
+        // The model cards give the size of the full precision file, so
+        // quantized variants report their size once they are cached
+        let card_size = traits.filter(|_| !quantized).map(|t| t.size_mb * 1024 * 1024);
+        let approx_size = cached.size.or(card_size).unwrap_or(0);
+
+        ModelInfoC {
+            model_code: c_string(model_code),
+            description: c_string(description),
+            dim,
+            pooling,
+            max_tokens,
+            query_prefix: c_string(traits.map_or("", |t| t.query_prefix)),
+            passage_prefix: c_string(traits.map_or("", |t| t.passage_prefix)),
+            model_file: c_string(model_file),
+            approx_size,
+            quantized,
+            multilingual: traits.map_or(false, |t| t.multilingual),
+        }
     }
+}
 
+fn model_info_vec(model_infos: Vec<ModelInfoC>) -> *mut ModelInfoVec {
+    // A boxed slice has capacity == len, as fastembed_model_info_vec_free expects
+    let model_infos = model_infos.into_boxed_slice();
     let len = model_infos.len();
-    let models_ptr = model_infos.as_mut_ptr();
-    std::mem::forget(model_infos);
+    let models_ptr = Box::into_raw(model_infos) as *mut ModelInfoC;
 
     Box::into_raw(Box::new(ModelInfoVec {
         models: models_ptr,
@@ -729,101 +3235,201 @@ pub extern "C" fn fastembed_text_embedding_list_supported_models() -> *mut Model
     }))
 }
 
+// Text Embedding Model Listing
+#[no_mangle]
+pub extern "C" fn fastembed_text_embedding_list_supported_models(cache_dir: *const c_char) -> *mut ModelInfoVec {
+    guard("fastembed_text_embedding_list_supported_models", ptr::null_mut(), ptr::null_mut(), || {
+        let cache_dir = listing_cache_dir(cache_dir);
+        let model_infos = TextEmbedding::list_supported_models()
+            .iter()
+            .map(|m| {
+                let pooling = pooling_to_c(TextEmbedding::get_default_pooling_method(&m.model));
+                ModelInfoC::new(
+                    &m.model,
+                    &m.model_code,
+                    &m.description,
+                    m.dim,
+                    &onnx_files(&m.model_file, &m.additional_files),
+                    pooling,
+                    InitOptions::new(m.model.clone()).max_length,
+                    &cache_dir,
+                )
+            })
+            .collect();
+        model_info_vec(model_infos)
+    })
+}
+
 #[no_mangle]
 pub extern "C" fn fastembed_model_info_vec_free(vec: *mut ModelInfoVec) {
-    if !vec.is_null() {
-        unsafe {
-            let vec = Box::from_raw(vec);
-            let models = Vec::from_raw_parts(vec.models, vec.len, vec.len);
-            for model in models {
-                if !model.model_code.is_null() {
-                    let _ = CString::from_raw(model.model_code);
-                }
-                if !model.description.is_null() {
-                    let _ = CString::from_raw(model.description);
+    guard("fastembed_model_info_vec_free", ptr::null_mut(), (), || {
+        if !vec.is_null() {
+            unsafe {
+                let vec = Box::from_raw(vec);
+                let models = Vec::from_raw_parts(vec.models, vec.len, vec.len);
+                for model in models {
+                    for s in [
+                        model.model_code,
+                        model.description,
+                        model.query_prefix,
+                        model.passage_prefix,
+                        model.model_file,
+                    ] {
+                        if !s.is_null() {
+                            let _ = CString::from_raw(s);
+                        }
+                    }
                 }
             }
         }
-    }
+    })
 }
 
 // Sparse Text Embedding Model Listing
 #[no_mangle]
-pub extern "C" fn fastembed_sparse_text_embedding_list_supported_models() -> *mut ModelInfoVec {
-    let models = SparseTextEmbedding::list_supported_models();
-    let mut model_infos = Vec::new();
-
-    for model in models {
-        let model_code = CString::new(model.model_code).unwrap_or_else(|_| CString::new("").unwrap());
-        let description = CString::new(model.description).unwrap_or_else(|_| CString::new("").unwrap());
-        
-        model_infos.push(ModelInfoC {
-            model_code: model_code.into_raw(),
-            description: description.into_raw(),
-            dim: model.dim,
-        });
-    }
-
-    let len = model_infos.len();
-    let models_ptr = model_infos.as_mut_ptr();
-    std::mem::forget(model_infos);
-
-    Box::into_raw(Box::new(ModelInfoVec {
-        models: models_ptr,
-        len,
-    }))
+pub extern "C" fn fastembed_sparse_text_embedding_list_supported_models(cache_dir: *const c_char) -> *mut ModelInfoVec {
+    guard("fastembed_sparse_text_embedding_list_supported_models", ptr::null_mut(), ptr::null_mut(), || {
+        let cache_dir = listing_cache_dir(cache_dir);
+        let model_infos = SparseTextEmbedding::list_supported_models()
+            .iter()
+            .map(|m| {
+                ModelInfoC::new(
+                    &m.model,
+                    &m.model_code,
+                    &m.description,
+                    m.dim,
+                    &onnx_files(&m.model_file, &m.additional_files),
+                    FASTEMBED_POOLING_DEFAULT,
+                    SparseInitOptions::new(m.model.clone()).max_length,
+                    &cache_dir,
+                )
+            })
+            .collect();
+        model_info_vec(model_infos)
+    })
 }
 
 // Image Embedding Model Listing
 #[no_mangle]
-pub extern "C" fn fastembed_image_embedding_list_supported_models() -> *mut ModelInfoVec {
-    let models = ImageEmbedding::list_supported_models();
-    let mut model_infos = Vec::new();
-
-    for model in models {
-        let model_code = CString::new(model.model_code).unwrap_or_else(|_| CString::new("").unwrap());
-        let description = CString::new(model.description).unwrap_or_else(|_| CString::new("").unwrap());
-        
-        model_infos.push(ModelInfoC {
-            model_code: model_code.into_raw(),
-            description: description.into_raw(),
-            dim: model.dim,
-        });
-    }
-
-    let len = model_infos.len();
-    let models_ptr = model_infos.as_mut_ptr();
-    std::mem::forget(model_infos);
-
-    Box::into_raw(Box::new(ModelInfoVec {
-        models: models_ptr,
-        len,
-    }))
+pub extern "C" fn fastembed_image_embedding_list_supported_models(cache_dir: *const c_char) -> *mut ModelInfoVec {
+    guard("fastembed_image_embedding_list_supported_models", ptr::null_mut(), ptr::null_mut(), || {
+        let cache_dir = listing_cache_dir(cache_dir);
+        let model_infos = ImageEmbedding::list_supported_models()
+            .iter()
+            .map(|m| {
+                ModelInfoC::new(
+                    &m.model,
+                    &m.model_code,
+                    &m.description,
+                    m.dim,
+                    &onnx_files(&m.model_file, &m.additional_files),
+                    FASTEMBED_POOLING_DEFAULT,
+                    0,
+                    &cache_dir,
+                )
+            })
+            .collect();
+        model_info_vec(model_infos)
+    })
 }
 
 // Text Rerank Model Listing
 #[no_mangle]
-pub extern "C" fn fastembed_text_rerank_list_supported_models() -> *mut ModelInfoVec {
-    let models = TextRerank::list_supported_models();
-    let mut model_infos = Vec::new();
-
-    for model in models {
-        let model_code = CString::new(model.model_code).unwrap_or_else(|_| CString::new("").unwrap());
-        let description = CString::new(model.description).unwrap_or_else(|_| CString::new("").unwrap());
-        
-        model_infos.push(ModelInfoC {
-            model_code: model_code.into_raw(),
-            description: description.into_raw(),
-            dim: 0, // Reranker models don't have dimensions
+pub extern "C" fn fastembed_text_rerank_list_supported_models(cache_dir: *const c_char) -> *mut ModelInfoVec {
+    guard("fastembed_text_rerank_list_supported_models", ptr::null_mut(), ptr::null_mut(), || {
+        let cache_dir = listing_cache_dir(cache_dir);
+        let model_infos = TextRerank::list_supported_models()
+            .iter()
+            .map(|m| {
+                ModelInfoC::new(
+                    &m.model,
+                    &m.model_code,
+                    &m.description,
+                    0,
+                    &onnx_files(&m.model_file, &m.additional_files),
+                    FASTEMBED_POOLING_DEFAULT,
+                    RerankInitOptions::new(m.model.clone()).max_length,
+                    &cache_dir,
+                )
+            })
+            .collect();
+        model_info_vec(model_infos)
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    /// Returns the code and message of an error and frees it.
+    fn take_error(error: *mut FastEmbedError) -> (i32, String) {
+        assert!(!error.is_null(), "Expected an error");
+        let (code, message) = unsafe {
+            let e = &*error;
+            (e.code, CStr::from_ptr(e.message).to_string_lossy().into_owned())
+        };
+        fastembed_error_free(error);
+        (code, message)
+    }
+
+    #[test]
+    fn guard_returns_the_result_without_a_panic() {
+        let mut error = ptr::null_mut();
+        assert_eq!(guard("test_function", &mut error, 0, || 42), 42);
+        assert!(error.is_null());
+    }
+
+    #[test]
+    fn guard_reports_panics_as_errors() {
+        let mut error = ptr::null_mut();
+        let result = guard("test_function", &mut error, -1, || -> i32 { panic!("boom {}", 7) });
+        assert_eq!(result, -1);
+
+        let (code, message) = take_error(error);
+        assert_eq!(code, FASTEMBED_ERROR_PANIC);
+        assert!(message.starts_with("Panic in test_function: boom 7 at "), "{}", message);
+        assert!(message.contains("lib.rs:"), "Expected the panic location in {}", message);
+        assert!(LAST_PANIC.with(|last| last.borrow().is_none()));
+    }
+
+    #[test]
+    fn guard_tolerates_a_null_error_pointer() {
+        assert!(!guard("test_function", ptr::null_mut(), false, || -> bool { panic!("boom") }));
+    }
+
+    #[test]
+    fn guard_ignores_summaries_of_panics_caught_elsewhere() {
+        LAST_PANIC.with(|last| *last.borrow_mut() = Some("at stale.rs:1:1".to_string()));
+        let mut error = ptr::null_mut();
+        guard("test_function", &mut error, (), || {
+            // A panic re-raised from another thread was not recorded here
+            let payload = std::thread::spawn(|| panic!("worker")).join().unwrap_err();
+            panic::resume_unwind(payload)
         });
+
+        let (code, message) = take_error(error);
+        assert_eq!(code, FASTEMBED_ERROR_PANIC);
+        assert_eq!(message, "Panic in test_function: worker backtrace unavailable");
     }
 
-    let len = model_infos.len();
-    let models_ptr = model_infos.as_mut_ptr();
-    std::mem::forget(model_infos);
+    #[test]
+    fn panic_message_reads_string_payloads() {
+        assert_eq!(panic_message(&"static"), "static");
+        assert_eq!(panic_message(&"owned".to_string()), "owned");
+        assert_eq!(panic_message(&42), "unknown panic payload");
+    }
 
-    Box::into_raw(Box::new(ModelInfoVec {
-        models: models_ptr,
-        len,
-    }))
+    #[test]
+    fn backtrace_summary_skips_panic_machinery() {
+        let summary = backtrace_summary(&Backtrace::force_capture());
+        assert!(
+            summary == "backtrace unavailable" || summary.starts_with("backtrace:\n  "),
+            "Unexpected summary {}",
+            summary
+        );
+        for line in summary.lines().skip(1) {
+            assert!(!line.trim_start().starts_with("std::"), "Unexpected frame {}", line);
+        }
+        assert!(summary.lines().count() <= PANIC_BACKTRACE_FRAMES + 1);
+    }
 }